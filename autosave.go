@@ -0,0 +1,197 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+var (
+	autosaveMutex   sync.Mutex
+	autosaveStopped bool
+	autosavedData   string
+)
+
+func stateDirectory() (string, error) {
+	if directory := os.Getenv("XDG_STATE_HOME"); directory != "" {
+		return directory, nil
+	}
+	homeDirectory, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDirectory, ".local", "state"), nil
+}
+
+func recoveryDirectory() (string, error) {
+	directory, err := stateDirectory()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(directory, "termcanvas"), nil
+}
+
+func recoveryPath() (string, error) {
+	directory, err := recoveryDirectory()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(directory, fmt.Sprintf("recovery-%v.csv", os.Getpid())), nil
+}
+
+func processRunning(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	err = process.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+func writeFileAtomic(filePath string, data []byte, permissions os.FileMode) error {
+	file, err := os.CreateTemp(filepath.Dir(filePath), "."+filepath.Base(filePath)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	temporaryPath := file.Name()
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		os.Remove(temporaryPath)
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		os.Remove(temporaryPath)
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(temporaryPath)
+		return err
+	}
+	if info, err := os.Stat(filePath); err == nil {
+		permissions = info.Mode().Perm()
+	}
+	if err := os.Chmod(temporaryPath, permissions); err != nil {
+		os.Remove(temporaryPath)
+		return err
+	}
+	if err := os.Rename(temporaryPath, filePath); err != nil {
+		os.Remove(temporaryPath)
+		return err
+	}
+	return nil
+}
+
+func staleRecoveryPaths() []string {
+	directory, err := recoveryDirectory()
+	if err != nil {
+		return nil
+	}
+	filePaths, err := filepath.Glob(filepath.Join(directory, "recovery-*.csv"))
+	if err != nil {
+		return nil
+	}
+	var stalePaths []string
+	for _, filePath := range filePaths {
+		pid, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(filepath.Base(filePath), "recovery-"), ".csv"))
+		if err != nil || pid == os.Getpid() || processRunning(pid) {
+			continue
+		}
+		stalePaths = append(stalePaths, filePath)
+	}
+	return stalePaths
+}
+
+func loadRecovery() (string, bool) {
+	newestPath, newestTime := "", time.Time{}
+	for _, filePath := range staleRecoveryPaths() {
+		info, err := os.Stat(filePath)
+		if err != nil {
+			continue
+		}
+		if newestPath == "" || info.ModTime().After(newestTime) {
+			newestPath, newestTime = filePath, info.ModTime()
+		}
+	}
+	if newestPath == "" {
+		return "", false
+	}
+	fileData, err := os.ReadFile(newestPath)
+	if err != nil {
+		return "", false
+	}
+	return string(fileData), true
+}
+
+func removeStaleRecoveries() {
+	for _, filePath := range staleRecoveryPaths() {
+		os.Remove(filePath)
+	}
+}
+
+func writeRecovery() error {
+	autosaveMutex.Lock()
+	defer autosaveMutex.Unlock()
+
+	if autosaveStopped {
+		return nil
+	}
+	filePath, err := recoveryPath()
+	if err != nil {
+		return err
+	}
+	data, empty := dumpData()
+	if data == autosavedData {
+		return nil
+	}
+	if empty {
+		if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		autosavedData = data
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(filePath, []byte(data), 0600); err != nil {
+		return err
+	}
+	autosavedData = data
+	return nil
+}
+
+func autosave(interval time.Duration) {
+	if interval == 0 {
+		return
+	}
+	for range time.Tick(interval) {
+		writeRecovery()
+	}
+}
+
+func stopAutosave() {
+	if autosaveInterval != 0 {
+		writeRecovery()
+	}
+
+	autosaveMutex.Lock()
+	defer autosaveMutex.Unlock()
+	autosaveStopped = true
+}
+
+func removeRecovery() {
+	autosaveMutex.Lock()
+	defer autosaveMutex.Unlock()
+
+	autosavedData = ""
+	if filePath, err := recoveryPath(); err == nil {
+		os.Remove(filePath)
+	}
+}
@@ -0,0 +1,431 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+)
+
+const (
+	bannerDialogWidth = 60
+	bannerBrushLabel  = "Brush"
+)
+
+//go:embed fonts/*.flf
+var bundledFonts embed.FS
+
+var (
+	fontDirectories = []string{"/usr/share/figlet", "/usr/local/share/figlet", "/usr/share/figlet/fonts"}
+	bannerText      string
+	bannerFont      = "standard"
+	bannerBrush     = true
+	germanRunes     = []rune{196, 214, 220, 228, 246, 252, 223}
+	smushHierarchy  = []string{"|", "/\\", "[]", "{}", "()", "<>"}
+)
+
+type figletFont struct {
+	height     int
+	hardblank  rune
+	layout     int
+	characters map[rune][][]rune
+}
+
+type bannerDialog struct {
+	x, y     int
+	width    int
+	height   int
+	fonts    []string
+	font     *figletFont
+	err      error
+	accepted bool
+}
+
+func fontFiles() map[string]string {
+	files := make(map[string]string)
+	directories := fontDirectories
+	if directory, err := dataDirectory(); err == nil {
+		directories = append(directories, filepath.Join(directory, "termcanvas", "fonts"))
+	}
+	for _, directory := range directories {
+		filePaths, _ := filepath.Glob(filepath.Join(directory, "*.flf"))
+		for _, filePath := range filePaths {
+			files[strings.TrimSuffix(filepath.Base(filePath), ".flf")] = filePath
+		}
+	}
+	return files
+}
+
+func figletFonts() []string {
+	fonts := []string{}
+	entries, _ := bundledFonts.ReadDir("fonts")
+	for _, entry := range entries {
+		fonts = append(fonts, strings.TrimSuffix(entry.Name(), ".flf"))
+	}
+	for name := range fontFiles() {
+		if _, err := bundledFonts.Open("fonts/" + name + ".flf"); err != nil {
+			fonts = append(fonts, name)
+		}
+	}
+	sort.Strings(fonts)
+	return fonts
+}
+
+func loadFigletFont(name string) (*figletFont, error) {
+	fileData, err := bundledFonts.ReadFile("fonts/" + name + ".flf")
+	if err != nil {
+		filePath, ok := fontFiles()[name]
+		if !ok {
+			return nil, fmt.Errorf("there is no font called %v", name)
+		}
+		fileData, err = os.ReadFile(filePath)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return parseFigletFont(string(fileData))
+}
+
+func parseFigletFont(data string) (*figletFont, error) {
+	lines := strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n")
+	header := strings.Fields(lines[0])
+	if len(header) < 6 || !strings.HasPrefix(header[0], "flf2a") || len([]rune(header[0])) < 6 {
+		return nil, fmt.Errorf("not a FIGlet font")
+	}
+	values := make([]int, len(header))
+	for index := 1; index < len(header) && index < 8; index++ {
+		values[index], _ = strconv.Atoi(header[index])
+	}
+	font := &figletFont{
+		height:     values[1],
+		hardblank:  []rune(header[0])[5],
+		characters: make(map[rune][][]rune),
+	}
+	if font.height < 1 {
+		return nil, fmt.Errorf("invalid font height")
+	}
+	if len(header) >= 8 {
+		font.layout = values[7]
+	} else if values[4] == 0 {
+		font.layout = 64
+	} else if values[4] > 0 {
+		font.layout = values[4]&31 | 128
+	}
+
+	index := 1 + values[5]
+	readCharacter := func() [][]rune {
+		if index+font.height > len(lines) {
+			return nil
+		}
+		rows := make([][]rune, font.height)
+		for row := range rows {
+			letters := []rune(strings.TrimRight(lines[index+row], " \t"))
+			if len(letters) > 0 {
+				endmark := letters[len(letters)-1]
+				for len(letters) > 0 && letters[len(letters)-1] == endmark {
+					letters = letters[:len(letters)-1]
+				}
+			}
+			rows[row] = letters
+		}
+		index += font.height
+		return rows
+	}
+	for letter := rune(32); letter <= 126; letter++ {
+		rows := readCharacter()
+		if rows == nil {
+			return nil, fmt.Errorf("the font is missing characters")
+		}
+		font.characters[letter] = rows
+	}
+	for _, letter := range germanRunes {
+		if rows := readCharacter(); rows != nil {
+			font.characters[letter] = rows
+		}
+	}
+	for index < len(lines) {
+		fields := strings.Fields(lines[index])
+		index++
+		if len(fields) == 0 {
+			continue
+		}
+		code, err := strconv.ParseInt(fields[0], 0, 32)
+		rows := readCharacter()
+		if err != nil || rows == nil {
+			break
+		}
+		font.characters[rune(code)] = rows
+	}
+	return font, nil
+}
+
+func (font *figletFont) smushLetters(left, right rune) rune {
+	if left == ' ' {
+		return right
+	}
+	if right == ' ' {
+		return left
+	}
+	if font.layout&128 == 0 {
+		return 0
+	}
+	rules := font.layout & 63
+	if rules == 0 {
+		if left == font.hardblank {
+			return right
+		}
+		return left
+	}
+	if left == font.hardblank || right == font.hardblank {
+		if rules&32 != 0 && left == right {
+			return left
+		}
+		return 0
+	}
+	if rules&1 != 0 && left == right {
+		return left
+	}
+	if rules&2 != 0 {
+		if left == '_' && strings.ContainsRune("|/\\[]{}()<>", right) {
+			return right
+		}
+		if right == '_' && strings.ContainsRune("|/\\[]{}()<>", left) {
+			return left
+		}
+	}
+	if rules&4 != 0 {
+		for leftClass, letters := range smushHierarchy {
+			for rightClass, otherLetters := range smushHierarchy {
+				if strings.ContainsRune(letters, left) && strings.ContainsRune(otherLetters, right) {
+					if rightClass > leftClass {
+						return right
+					} else if leftClass > rightClass {
+						return left
+					}
+				}
+			}
+		}
+	}
+	if rules&8 != 0 {
+		pair := string([]rune{left, right})
+		if pair == "[]" || pair == "][" || pair == "{}" || pair == "}{" || pair == "()" || pair == ")(" {
+			return '|'
+		}
+	}
+	if rules&16 != 0 {
+		switch string([]rune{left, right}) {
+		case "/\\":
+			return '|'
+		case "\\/":
+			return 'Y'
+		case "><":
+			return 'X'
+		}
+	}
+	return 0
+}
+
+func (font *figletFont) smushAmount(output, character [][]rune) int {
+	if font.layout&(64|128) == 0 {
+		return 0
+	}
+	amount := len(character[0])
+	for row := range character {
+		outputBoundary := len(output[row]) - 1
+		for outputBoundary >= 0 && output[row][outputBoundary] == ' ' {
+			outputBoundary--
+		}
+		characterBoundary := 0
+		for characterBoundary < len(character[row]) && character[row][characterBoundary] == ' ' {
+			characterBoundary++
+		}
+		rowAmount := characterBoundary + len(output[row]) - 1 - outputBoundary
+		if outputBoundary < 0 {
+			rowAmount++
+		} else if characterBoundary < len(character[row]) &&
+			font.smushLetters(output[row][outputBoundary], character[row][characterBoundary]) != 0 {
+			rowAmount++
+		}
+		if rowAmount < amount {
+			amount = rowAmount
+		}
+	}
+	return amount
+}
+
+func (font *figletFont) render(text string) [][]rune {
+	output := make([][]rune, font.height)
+	for _, letter := range text {
+		character, ok := font.characters[letter]
+		if !ok || len(character[0]) == 0 {
+			continue
+		}
+		amount := font.smushAmount(output, character)
+		for row := range output {
+			if amount > len(output[row]) {
+				amount = len(output[row])
+			}
+		}
+		for row := range output {
+			for column := 0; column < amount && column < len(character[row]); column++ {
+				outputColumn := len(output[row]) - amount + column
+				smushedLetter := font.smushLetters(output[row][outputColumn], character[row][column])
+				if smushedLetter == 0 {
+					smushedLetter = character[row][column]
+				}
+				output[row][outputColumn] = smushedLetter
+			}
+			if amount < len(character[row]) {
+				output[row] = append(output[row], character[row][amount:]...)
+			}
+		}
+	}
+	return output
+}
+
+func bannerCells(font *figletFont, text string, letter rune, style tcell.Style, useBrush bool) map[point]cell {
+	cells := make(map[point]cell)
+	letterWidth := runewidth.RuneWidth(letter)
+	for row, letters := range font.render(text) {
+		x := 0
+		for _, fontLetter := range letters {
+			width := runewidth.RuneWidth(fontLetter)
+			if width < 1 {
+				width = 1
+			}
+			if fontLetter != ' ' && fontLetter != font.hardblank {
+				if !useBrush {
+					cells[point{x, row}] = cell{fontLetter, style}
+				} else if letterWidth < 2 || x%2 == 0 {
+					cells[point{x, row}] = cell{letter, style}
+				}
+			}
+			x += width
+		}
+	}
+	return normalizeCells(cells)
+}
+
+func newBannerDialog() *bannerDialog {
+	dialog := &bannerDialog{fonts: figletFonts()}
+	dialog.selectFont(bannerFont)
+	return dialog
+}
+
+func (dialog *bannerDialog) selectFont(name string) {
+	bannerFont = name
+	dialog.font, dialog.err = loadFigletFont(name)
+}
+
+func (dialog *bannerDialog) cycleFont(offset int) {
+	if len(dialog.fonts) == 0 {
+		return
+	}
+	index := 0
+	for fontIndex, name := range dialog.fonts {
+		if name == bannerFont {
+			index = fontIndex
+		}
+	}
+	dialog.selectFont(dialog.fonts[(index+offset+len(dialog.fonts))%len(dialog.fonts)])
+}
+
+func (dialog *bannerDialog) cells() map[point]cell {
+	if dialog.font == nil {
+		return nil
+	}
+	return bannerCells(dialog.font, bannerText, brush, paintStyle(), bannerBrush)
+}
+
+func (dialog *bannerDialog) draw(screen tcell.Screen) {
+	width, _ := screen.Size()
+	previewHeight := 1
+	if dialog.font != nil {
+		previewHeight = dialog.font.height
+	}
+	dialog.width, dialog.height = bannerDialogWidth, previewHeight+7
+	if dialog.width > width {
+		dialog.width = width
+	}
+	dialog.x, dialog.y = centeredBox(screen, dialog.width, dialog.height)
+
+	defaultStyle := tcell.StyleDefault.Foreground(tcell.ColorWhite)
+	drawBox(screen, dialog.x, dialog.y, dialog.x+dialog.width-1, dialog.y+dialog.height-1, "Banner", defaultStyle)
+	drawText(screen, dialog.x+2, dialog.y+1, "Text", defaultStyle)
+	drawText(screen, dialog.x+8, dialog.y+1, bannerText+"_", defaultStyle)
+	drawText(screen, dialog.x+2, dialog.y+2, "Font", defaultStyle)
+	drawText(screen, dialog.x+8, dialog.y+2, "◀ "+bannerFont+" ▶", defaultStyle)
+	brushStyle := defaultStyle
+	if bannerBrush {
+		brushStyle = brushStyle.Reverse(true)
+	}
+	drawText(screen, dialog.x+dialog.width-len(bannerBrushLabel)-2, dialog.y+2, bannerBrushLabel, brushStyle)
+
+	if dialog.err != nil {
+		drawText(screen, dialog.x+2, dialog.y+4, "Unable to load font: "+dialog.err.Error(), defaultStyle)
+	} else {
+		colorCount := screen.Colors()
+		for cellPoint, bannerCell := range dialog.cells() {
+			if cellPoint.x < dialog.width-4 && bannerCell.character != 0 {
+				screen.SetContent(dialog.x+2+cellPoint.x, dialog.y+4+cellPoint.y, bannerCell.character, nil, adaptStyle(bannerCell.style, colorCount))
+			}
+		}
+	}
+	drawText(screen, dialog.x+2, dialog.y+dialog.height-2, pickerOkLabel, defaultStyle)
+	drawText(screen, dialog.x+3+len(pickerOkLabel), dialog.y+dialog.height-2, pickerCancelLabel, defaultStyle)
+}
+
+func (dialog *bannerDialog) handleEvent(event tcell.Event) bool {
+	switch event := event.(type) {
+	case *tcell.EventKey:
+		switch event.Key() {
+		case tcell.KeyEscape:
+			return true
+		case tcell.KeyEnter:
+			dialog.accepted = dialog.font != nil && bannerText != ""
+			return true
+		case tcell.KeyBackspace, tcell.KeyBackspace2:
+			if len(bannerText) > 0 {
+				letters := []rune(bannerText)
+				bannerText = string(letters[:len(letters)-1])
+			}
+		case tcell.KeyLeft, tcell.KeyUp:
+			dialog.cycleFont(-1)
+		case tcell.KeyRight, tcell.KeyDown:
+			dialog.cycleFont(1)
+		case tcell.KeyCtrlB:
+			bannerBrush = !bannerBrush
+		case tcell.KeyRune:
+			bannerText += string(event.Rune())
+		}
+	case *tcell.EventMouse:
+		if event.Buttons() != tcell.Button1 {
+			return false
+		}
+		x, y := event.Position()
+		brushOffset := dialog.x + dialog.width - len(bannerBrushLabel) - 2
+		if y == dialog.y+2 {
+			if x == dialog.x+8 {
+				dialog.cycleFont(-1)
+			} else if x == dialog.x+10+len([]rune(bannerFont))+1 {
+				dialog.cycleFont(1)
+			} else if x >= brushOffset && x < brushOffset+len(bannerBrushLabel) {
+				bannerBrush = !bannerBrush
+			}
+		} else if y == dialog.y+dialog.height-2 {
+			if x >= dialog.x+2 && x < dialog.x+2+len(pickerOkLabel) {
+				dialog.accepted = dialog.font != nil && bannerText != ""
+				return true
+			} else if x >= dialog.x+3+len(pickerOkLabel) && x < dialog.x+3+len(pickerOkLabel)+len(pickerCancelLabel) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+const (
+	borderDialogWidth = 30
+	armUp             = 0
+	armRight          = 1
+	armDown           = 2
+	armLeft           = 3
+)
+
+type borderArms [4]int
+
+type borderDialog struct {
+	x, y int
+}
+
+var (
+	borders        = []string{"single", "double", "rounded", "heavy", "ascii"}
+	selectedBorder = "single"
+	borderWeights  = map[string]int{
+		"single":  1,
+		"double":  3,
+		"rounded": 1,
+		"heavy":   2,
+		"ascii":   1,
+	}
+	runeArms = armedRunes(
+		"─0101", "━0202", "│1010", "┃2020",
+		"┌0110", "┍0210", "┎0120", "┏0220", "┐0011", "┑0012", "┒0021", "┓0022",
+		"└1100", "┕1200", "┖2100", "┗2200", "┘1001", "┙1002", "┚2001", "┛2002",
+		"├1110", "┝1210", "┞2110", "┟1120", "┠2120", "┡2210", "┢1220", "┣2220",
+		"┤1011", "┥1012", "┦2011", "┧1021", "┨2021", "┩2012", "┪1022", "┫2022",
+		"┬0111", "┭0112", "┮0211", "┯0212", "┰0121", "┱0122", "┲0221", "┳0222",
+		"┴1101", "┵1102", "┶1201", "┷1202", "┸2101", "┹2102", "┺2201", "┻2202",
+		"┼1111", "┽1112", "┾1211", "┿1212", "╀2111", "╁1121", "╂2121", "╃2112",
+		"╄2211", "╅1122", "╆1221", "╇2212", "╈1222", "╉2122", "╊2221", "╋2222",
+		"═0303", "║3030", "╒0310", "╓0130", "╔0330", "╕0013", "╖0031", "╗0033",
+		"╘1300", "╙3100", "╚3300", "╛1003", "╜3001", "╝3003", "╞1310", "╟3130",
+		"╠3330", "╡1013", "╢3031", "╣3033", "╤0313", "╥0131", "╦0333", "╧1303",
+		"╨3101", "╩3303", "╪1313", "╫3131", "╬3333",
+		"╴0001", "╵1000", "╶0100", "╷0010", "╸0002", "╹2000", "╺0200", "╻0020",
+	)
+	armsRunes    = reversedArms(runeArms)
+	roundedArms  = armedRunes("╭0110", "╮0011", "╯1001", "╰1100")
+	roundedRunes = reversedArms(roundedArms)
+	asciiArms    = armedRunes("-0101", "|1010", "+1111")
+)
+
+func armedRunes(specifications ...string) map[rune]borderArms {
+	mapping := make(map[rune]borderArms)
+	for _, specification := range specifications {
+		letters := []rune(specification)
+		var arms borderArms
+		for index := range arms {
+			arms[index] = int(letters[index+1] - '0')
+		}
+		mapping[letters[0]] = arms
+	}
+	return mapping
+}
+
+func reversedArms(mapping map[rune]borderArms) map[borderArms]rune {
+	reversedMapping := make(map[borderArms]rune)
+	for letter, arms := range mapping {
+		reversedMapping[arms] = letter
+	}
+	return reversedMapping
+}
+
+func letterArms(letter rune) (borderArms, bool) {
+	if arms, ok := runeArms[letter]; ok {
+		return arms, true
+	}
+	if arms, ok := roundedArms[letter]; ok {
+		return arms, true
+	}
+	arms, ok := asciiArms[letter]
+	return arms, ok
+}
+
+func borderRune(arms borderArms, border string) rune {
+	if border == "ascii" {
+		horizontal := arms[armLeft] != 0 || arms[armRight] != 0
+		vertical := arms[armUp] != 0 || arms[armDown] != 0
+		if horizontal && vertical {
+			return '+'
+		} else if vertical {
+			return '|'
+		}
+		return '-'
+	}
+	if border == "rounded" {
+		if letter, ok := roundedRunes[arms]; ok {
+			return letter
+		}
+	}
+	if letter, ok := armsRunes[arms]; ok {
+		return letter
+	}
+	for index := range arms {
+		if arms[index] != 0 {
+			arms[index] = borderWeights[border]
+		}
+	}
+	if letter, ok := armsRunes[arms]; ok {
+		return letter
+	}
+	return ' '
+}
+
+func borderCells(x1, y1, x2, y2 int, border string) map[point]rune {
+	if y2 < y1 {
+		y1, y2 = y2, y1
+	}
+	if x2 < x1 {
+		x1, x2 = x2, x1
+	}
+
+	weight := borderWeights[border]
+	cellArms := make(map[point]borderArms)
+	if y1 == y2 {
+		for col := x1; col <= x2; col++ {
+			cellArms[point{col, y1}] = borderArms{0, weight, 0, weight}
+		}
+	} else if x1 == x2 {
+		for row := y1; row <= y2; row++ {
+			cellArms[point{x1, row}] = borderArms{weight, 0, weight, 0}
+		}
+	} else {
+		for col := x1 + 1; col < x2; col++ {
+			cellArms[point{col, y1}] = borderArms{0, weight, 0, weight}
+			cellArms[point{col, y2}] = borderArms{0, weight, 0, weight}
+		}
+		for row := y1 + 1; row < y2; row++ {
+			cellArms[point{x1, row}] = borderArms{weight, 0, weight, 0}
+			cellArms[point{x2, row}] = borderArms{weight, 0, weight, 0}
+		}
+		cellArms[point{x1, y1}] = borderArms{0, weight, weight, 0}
+		cellArms[point{x2, y1}] = borderArms{0, 0, weight, weight}
+		cellArms[point{x1, y2}] = borderArms{weight, weight, 0, 0}
+		cellArms[point{x2, y2}] = borderArms{weight, 0, 0, weight}
+	}
+
+	cells := make(map[point]rune)
+	for cellPoint, arms := range cellArms {
+		existingLetter, _, _ := canvas.get(cellPoint.x, cellPoint.y)
+		if existingArms, ok := letterArms(existingLetter); ok {
+			for index := range arms {
+				if arms[index] == 0 {
+					arms[index] = existingArms[index]
+				}
+			}
+		}
+		cells[cellPoint] = borderRune(arms, border)
+	}
+	return cells
+}
+
+func drawBorder(screen tcell.Screen, x1, y1, x2, y2 int, style tcell.Style, border string, send bool) {
+	for cellPoint, letter := range borderCells(x1, y1, x2, y2, border) {
+		setContent(screen, cellPoint.x, cellPoint.y, letter, style, false)
+	}
+	if len(connections) > 0 && send {
+		foregroundColorName, backgroundColorName, attributes := formatStyle(style)
+		broadcastOnLayer(fmt.Sprintf(
+			"border:%v,%v,%v,%v,%v,%v,%v,%v\n",
+			x1,
+			y1,
+			x2,
+			y2,
+			border,
+			foregroundColorName,
+			backgroundColorName,
+			attributes,
+		))
+	}
+}
+
+func drawBorderPreview(screen tcell.Screen, x1, y1, x2, y2 int, style tcell.Style, border string) {
+	style = adaptStyle(style, screen.Colors())
+	for cellPoint, letter := range borderCells(x1, y1, x2, y2, border) {
+		if cellPoint.y >= toolbarHeight {
+			screen.SetContent(cellPoint.x, cellPoint.y, letter, nil, style)
+		}
+	}
+}
+
+func (dialog *borderDialog) draw(screen tcell.Screen) {
+	dialogHeight := len(borders) + 4
+	dialog.x, dialog.y = centeredBox(screen, borderDialogWidth, dialogHeight)
+
+	defaultStyle := tcell.StyleDefault.Foreground(tcell.ColorWhite)
+	drawBox(screen, dialog.x, dialog.y, dialog.x+borderDialogWidth-1, dialog.y+dialogHeight-1, "Border Style", defaultStyle)
+	for index, border := range borders {
+		style := defaultStyle
+		if border == selectedBorder {
+			style = style.Reverse(true)
+		}
+		weight := borderWeights[border]
+		sample := string([]rune{
+			borderRune(borderArms{0, weight, weight, 0}, border),
+			borderRune(borderArms{0, weight, 0, weight}, border),
+			borderRune(borderArms{0, weight, weight, weight}, border),
+			borderRune(borderArms{0, weight, 0, weight}, border),
+			borderRune(borderArms{0, 0, weight, weight}, border),
+		})
+		drawText(screen, dialog.x+2, dialog.y+1+index, sample, defaultStyle)
+		drawText(screen, dialog.x+9, dialog.y+1+index, border, style)
+	}
+	drawText(screen, dialog.x+2, dialog.y+dialogHeight-2, pickerOkLabel, defaultStyle)
+}
+
+func (dialog *borderDialog) handleEvent(event tcell.Event) bool {
+	selectedIndex := 0
+	for index, border := range borders {
+		if border == selectedBorder {
+			selectedIndex = index
+		}
+	}
+	switch event := event.(type) {
+	case *tcell.EventKey:
+		switch event.Key() {
+		case tcell.KeyEscape, tcell.KeyEnter:
+			return true
+		case tcell.KeyUp:
+			selectedBorder = borders[(selectedIndex+len(borders)-1)%len(borders)]
+		case tcell.KeyDown:
+			selectedBorder = borders[(selectedIndex+1)%len(borders)]
+		}
+	case *tcell.EventMouse:
+		if event.Buttons() != tcell.Button1 {
+			return false
+		}
+		x, y := event.Position()
+		row := y - dialog.y - 1
+		if row >= 0 && row < len(borders) && x > dialog.x && x < dialog.x+borderDialogWidth-1 {
+			selectedBorder = borders[row]
+		} else if y == dialog.y+len(borders)+2 && x >= dialog.x+2 && x < dialog.x+2+len(pickerOkLabel) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,405 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+)
+
+type point struct {
+	x, y int
+}
+
+type cell struct {
+	character rune
+	style     tcell.Style
+}
+
+type layer struct {
+	name    string
+	visible bool
+	locked  bool
+	cells   map[point]cell
+}
+
+type canvasState struct {
+	mutex    sync.RWMutex
+	layers   []*layer
+	active   *layer
+	target   *layer
+	width    int
+	height   int
+	revision int
+}
+
+var canvas = newCanvasState()
+
+func newLayer(name string) *layer {
+	return &layer{name: name, visible: true, cells: make(map[point]cell)}
+}
+
+func newCanvasState() *canvasState {
+	background := newLayer("Background")
+	return &canvasState{layers: []*layer{background}, active: background}
+}
+
+func transparentCell(character rune, style tcell.Style) bool {
+	_, backgroundColor, attributeMask := style.Decompose()
+	return character == ' ' && normalizeColor(backgroundColor) == tcell.ColorDefault && attributeMask == tcell.AttrNone
+}
+
+func (state *canvasState) drawingLayer() *layer {
+	if state.target != nil {
+		return state.target
+	}
+	return state.active
+}
+
+func (state *canvasState) set(x, y int, character rune, style tcell.Style) {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+
+	drawingLayer := state.drawingLayer()
+	if state.target == nil && drawingLayer.locked || !state.inBounds(x, y) {
+		return
+	}
+	state.revision++
+	if character == 0 {
+		if wideCell, ok := drawingLayer.cells[point{x - 1, y}]; ok && runewidth.RuneWidth(wideCell.character) == 2 {
+			return
+		}
+		character = ' '
+	}
+	drawingLayer.breakWideCell(x, y)
+	if transparentCell(character, style) {
+		delete(drawingLayer.cells, point{x, y})
+		return
+	}
+	drawingLayer.cells[point{x, y}] = cell{character, style}
+	if runewidth.RuneWidth(character) == 2 {
+		drawingLayer.breakWideCell(x+1, y)
+		drawingLayer.cells[point{x + 1, y}] = cell{0, style}
+	}
+}
+
+func (drawingLayer *layer) breakWideCell(x, y int) {
+	existingCell, ok := drawingLayer.cells[point{x, y}]
+	if !ok {
+		return
+	}
+	if existingCell.character == 0 {
+		if wideCell, ok := drawingLayer.cells[point{x - 1, y}]; ok && wideCell.character != 0 {
+			drawingLayer.cells[point{x - 1, y}] = cell{' ', wideCell.style}
+		}
+	} else if runewidth.RuneWidth(existingCell.character) == 2 {
+		if continuationCell, ok := drawingLayer.cells[point{x + 1, y}]; ok && continuationCell.character == 0 {
+			drawingLayer.cells[point{x + 1, y}] = cell{' ', continuationCell.style}
+		}
+	}
+}
+
+func (state *canvasState) get(x, y int) (rune, tcell.Style, bool) {
+	state.mutex.RLock()
+	defer state.mutex.RUnlock()
+
+	existingCell, ok := state.drawingLayer().cells[point{x, y}]
+	if !ok {
+		return ' ', tcell.StyleDefault, false
+	}
+	return existingCell.character, existingCell.style, true
+}
+
+func (state *canvasState) clear() {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+
+	drawingLayer := state.drawingLayer()
+	if state.target == nil && drawingLayer.locked {
+		return
+	}
+	drawingLayer.cells = make(map[point]cell)
+	state.revision++
+}
+
+func (state *canvasState) setTarget(name string) {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+
+	if name == "" {
+		state.target = state.layers[0]
+		return
+	}
+	state.target = state.findLayer(name)
+	if state.target == nil {
+		state.target = newLayer(name)
+		state.layers = append(state.layers, state.target)
+	}
+}
+
+func (state *canvasState) clearTarget() {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+
+	state.target = nil
+}
+
+func (state *canvasState) findLayer(name string) *layer {
+	for _, existingLayer := range state.layers {
+		if existingLayer.name == name {
+			return existingLayer
+		}
+	}
+	return nil
+}
+
+func (state *canvasState) layerIndex(targetLayer *layer) int {
+	for index, existingLayer := range state.layers {
+		if existingLayer == targetLayer {
+			return index
+		}
+	}
+	return -1
+}
+
+func (state *canvasState) activeLayer() *layer {
+	state.mutex.RLock()
+	defer state.mutex.RUnlock()
+
+	return state.active
+}
+
+func (state *canvasState) drawingLayerName() string {
+	state.mutex.RLock()
+	defer state.mutex.RUnlock()
+
+	return state.drawingLayer().name
+}
+
+func (state *canvasState) setActiveLayer(index int) {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+
+	if index >= 0 && index < len(state.layers) {
+		state.active = state.layers[index]
+	}
+}
+
+func validateLayerName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("the layer name is empty")
+	}
+	if strings.ContainsAny(name, ",\n") {
+		return fmt.Errorf("layer names can't contain commas")
+	}
+	return nil
+}
+
+func (state *canvasState) addLayer(name string) (*layer, error) {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+
+	name = strings.TrimSpace(name)
+	if err := validateLayerName(name); err != nil {
+		return nil, err
+	}
+	if state.findLayer(name) != nil {
+		return nil, fmt.Errorf("a layer called %v already exists", name)
+	}
+	addedLayer := newLayer(name)
+	index := state.layerIndex(state.active) + 1
+	state.layers = append(state.layers[:index], append([]*layer{addedLayer}, state.layers[index:]...)...)
+	state.active = addedLayer
+	state.revision++
+	return addedLayer, nil
+}
+
+func (state *canvasState) renameLayer(oldName, newName string) error {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+
+	newName = strings.TrimSpace(newName)
+	if err := validateLayerName(newName); err != nil {
+		return err
+	}
+	renamedLayer := state.findLayer(oldName)
+	if renamedLayer == nil {
+		return fmt.Errorf("there is no layer called %v", oldName)
+	}
+	if existingLayer := state.findLayer(newName); existingLayer != nil && existingLayer != renamedLayer {
+		return fmt.Errorf("a layer called %v already exists", newName)
+	}
+	renamedLayer.name = newName
+	state.revision++
+	return nil
+}
+
+func (state *canvasState) deleteLayer(name string) error {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+
+	deletedLayer := state.findLayer(name)
+	if deletedLayer == nil {
+		return fmt.Errorf("there is no layer called %v", name)
+	}
+	if len(state.layers) == 1 {
+		return fmt.Errorf("the last layer can't be deleted")
+	}
+	index := state.layerIndex(deletedLayer)
+	state.layers = append(state.layers[:index], state.layers[index+1:]...)
+	if state.active == deletedLayer {
+		if index >= len(state.layers) {
+			index = len(state.layers) - 1
+		}
+		state.active = state.layers[index]
+	}
+	state.revision++
+	return nil
+}
+
+func (state *canvasState) updateLayer(name string, index int, visible, locked bool) {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+
+	updatedLayer := state.findLayer(name)
+	if updatedLayer == nil {
+		updatedLayer = newLayer(name)
+	} else {
+		oldIndex := state.layerIndex(updatedLayer)
+		state.layers = append(state.layers[:oldIndex], state.layers[oldIndex+1:]...)
+	}
+	if index < 0 {
+		index = 0
+	} else if index > len(state.layers) {
+		index = len(state.layers)
+	}
+	state.layers = append(state.layers[:index], append([]*layer{updatedLayer}, state.layers[index:]...)...)
+	updatedLayer.visible = visible
+	updatedLayer.locked = locked
+	state.revision++
+}
+
+func (state *canvasState) snapshot() []layer {
+	state.mutex.RLock()
+	defer state.mutex.RUnlock()
+
+	layers := make([]layer, len(state.layers))
+	for index, existingLayer := range state.layers {
+		layers[index] = *existingLayer
+		layers[index].cells = make(map[point]cell, len(existingLayer.cells))
+		for cellPoint, existingCell := range existingLayer.cells {
+			layers[index].cells[cellPoint] = existingCell
+		}
+	}
+	return layers
+}
+
+func (state *canvasState) layerList() []layer {
+	state.mutex.RLock()
+	defer state.mutex.RUnlock()
+
+	layers := make([]layer, len(state.layers))
+	for index, existingLayer := range state.layers {
+		layers[index] = layer{name: existingLayer.name, visible: existingLayer.visible, locked: existingLayer.locked}
+	}
+	return layers
+}
+
+func (drawingLayer *layer) points() []point {
+	points := make([]point, 0, len(drawingLayer.cells))
+	for cellPoint := range drawingLayer.cells {
+		points = append(points, cellPoint)
+	}
+	sort.Slice(points, func(i, j int) bool {
+		if points[i].x == points[j].x {
+			return points[i].y < points[j].y
+		}
+		return points[i].x < points[j].x
+	})
+	return points
+}
+
+func (state *canvasState) changes() int {
+	state.mutex.RLock()
+	defer state.mutex.RUnlock()
+
+	return state.revision
+}
+
+func (state *canvasState) inBounds(x, y int) bool {
+	if state.width == 0 || state.height == 0 {
+		return true
+	}
+	return x >= 0 && x < state.width && y >= toolbarHeight && y < state.height+toolbarHeight
+}
+
+func (state *canvasState) size() (int, int) {
+	state.mutex.RLock()
+	defer state.mutex.RUnlock()
+
+	return state.width, state.height
+}
+
+func (state *canvasState) resize(width, height int) {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+
+	state.width, state.height = width, height
+	state.revision++
+	for _, existingLayer := range state.layers {
+		for cellPoint := range existingLayer.cells {
+			if !state.inBounds(cellPoint.x, cellPoint.y) {
+				delete(existingLayer.cells, cellPoint)
+			}
+		}
+		for cellPoint, existingCell := range existingLayer.cells {
+			if existingCell.character != 0 && runewidth.RuneWidth(existingCell.character) == 2 && !state.inBounds(cellPoint.x+1, cellPoint.y) {
+				existingLayer.cells[cellPoint] = cell{' ', existingCell.style}
+			}
+		}
+	}
+}
+
+func compositeCells(layers []layer) map[point]cell {
+	cells := make(map[point]cell)
+	for _, existingLayer := range layers {
+		if !existingLayer.visible {
+			continue
+		}
+		for cellPoint, existingCell := range existingLayer.cells {
+			cells[cellPoint] = existingCell
+		}
+	}
+	return cells
+}
+
+func (state *canvasState) draw(view *canvasView) {
+	state.mutex.RLock()
+	defer state.mutex.RUnlock()
+
+	x1, y1, x2, y2 := view.bounds()
+	colorCount := view.Colors()
+	outsideStyle := tcell.StyleDefault.Foreground(tcell.ColorGray)
+	for y := y1; y <= y2; y++ {
+		for x := x1; x <= x2; x++ {
+			if !state.inBounds(x, y) {
+				view.SetContent(x, y, '·', nil, outsideStyle)
+				continue
+			}
+			existingCell, ok := cell{}, false
+			for index := len(state.layers) - 1; index >= 0 && !ok; index-- {
+				if state.layers[index].visible {
+					existingCell, ok = state.layers[index].cells[point{x, y}]
+				}
+			}
+			if !ok {
+				view.SetContent(x, y, ' ', nil, tcell.StyleDefault)
+				continue
+			}
+			view.SetContent(x, y, existingCell.character, nil, adaptStyle(existingCell.style, colorCount))
+		}
+	}
+}
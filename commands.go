@@ -0,0 +1,321 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+type commandInfo struct {
+	name        string
+	arguments   string
+	description string
+}
+
+type commandLine struct {
+	input       string
+	accepted    bool
+	history     int
+	draft       string
+	completions []string
+	completion  int
+}
+
+const maxCommandHistory = 100
+
+var (
+	commands = []commandInfo{
+		{"w", "[file]", "Save the drawing (to the last file if no file is given)"},
+		{"e", "<file>", "Load a drawing"},
+		{"clear", "", "Clear the current layer"},
+		{"color", "<color>", "Set the primary color"},
+		{"secondary-color", "<color>", "Set the secondary color"},
+		{"tool", "<tool>", "Switch to a tool"},
+		{"brush", "<character>", "Set the brush character"},
+		{"palette", "[file]", "Load a palette (or the default one)"},
+		{"layer", "<name>", "Switch to a layer"},
+		{"mirror", "<mode>", "Set the symmetry mode"},
+		{"grid", "[size|on|off]", "Toggle the grid, or show it every size cells"},
+		{"guide", "<x|y> <position>", "Add a guide line (or remove the one already there)"},
+		{"zoom", "<level>", "Set the zoom level (25%, 50%, 100%, 200%, 400% or 800%)"},
+		{"resize", "<width>x<height>", "Crop the canvas to a size (0x0 for no limit)"},
+		{"kick", "<player>", "Disconnect a player from your server"},
+		{"q", "", "Exit termcanvas"},
+	}
+	commandAliases = map[string]string{
+		"write": "w",
+		"edit":  "e",
+		"quit":  "q",
+	}
+	commandHistory []string
+)
+
+func newCommandLine() *commandLine {
+	return &commandLine{history: len(commandHistory)}
+}
+
+func splitCommand(input string) (string, string) {
+	name, arguments, _ := strings.Cut(strings.TrimSpace(input), " ")
+	name = strings.ToLower(name)
+	if alias, ok := commandAliases[name]; ok {
+		name = alias
+	}
+	return name, strings.TrimSpace(arguments)
+}
+
+func parseColorName(name string) (string, bool) {
+	if strings.HasPrefix(name, "#") {
+		value, err := strconv.ParseUint(name[1:], 16, 32)
+		if err != nil || len(name) != 7 {
+			return name, false
+		}
+		return fmt.Sprintf("#%06X", value), true
+	}
+	name = strings.ToLower(name)
+	if _, ok := tcell.ColorNames[name]; ok || name == "default" || name == "reset" {
+		return name, true
+	}
+	return name, false
+}
+
+func parseSize(input string) (int, int, error) {
+	widthText, heightText, ok := strings.Cut(strings.ToLower(input), "x")
+	if !ok {
+		return 0, 0, fmt.Errorf("sizes look like 200x80")
+	}
+	width, err := strconv.Atoi(strings.TrimSpace(widthText))
+	if err != nil || width < 0 {
+		return 0, 0, fmt.Errorf("invalid width %q", widthText)
+	}
+	height, err := strconv.Atoi(strings.TrimSpace(heightText))
+	if err != nil || height < 0 {
+		return 0, 0, fmt.Errorf("invalid height %q", heightText)
+	}
+	if (width == 0) != (height == 0) {
+		return 0, 0, fmt.Errorf("the width and height must both be 0 to remove the limit")
+	}
+	return width, height, nil
+}
+
+func formatForPath(filePath string, formats []fileFormat) fileFormat {
+	extension := strings.ToLower(filepath.Ext(filePath))
+	for _, format := range formats {
+		if format.extension == extension {
+			return format
+		}
+	}
+	return formats[0]
+}
+
+func commandNames() []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, command := range commands {
+		names = append(names, command.name)
+		seen[command.name] = true
+	}
+	for _, action := range globalBindings {
+		if !seen[action] && action != "command-line" {
+			names = append(names, action)
+			seen[action] = true
+		}
+	}
+	return names
+}
+
+func argumentCandidates(name, argument string) []string {
+	var candidates []string
+	switch name {
+	case "w", "e", "palette":
+		candidates, _ = completePath(".", argument)
+		return candidates
+	case "color", "secondary-color":
+		candidates = append(candidates, colors...)
+		var colorNames []string
+		for colorName := range tcell.ColorNames {
+			colorNames = append(colorNames, colorName)
+		}
+		sort.Strings(colorNames)
+		candidates = append(candidates, colorNames...)
+	case "tool":
+		for _, tool := range tools {
+			candidates = append(candidates, strings.ToLower(tool))
+		}
+	case "layer":
+		for _, existingLayer := range canvas.layerList() {
+			candidates = append(candidates, existingLayer.name)
+		}
+	case "mirror":
+		candidates = symmetryModes
+	case "grid":
+		candidates = []string{"on", "off"}
+	case "guide":
+		candidates = []string{"x", "y"}
+	case "zoom":
+		for _, level := range zoomLevels {
+			candidates = append(candidates, level.name)
+		}
+	case "kick":
+		candidates = connectionNames()
+	}
+
+	var matches []string
+	for _, candidate := range candidates {
+		if strings.HasPrefix(strings.ToLower(candidate), strings.ToLower(argument)) {
+			matches = append(matches, candidate)
+		}
+	}
+	return matches
+}
+
+func completeCommand(input string) []string {
+	name, argument, hasArgument := strings.Cut(strings.TrimLeft(input, " "), " ")
+	var completions []string
+	if !hasArgument {
+		for _, commandName := range commandNames() {
+			if strings.HasPrefix(commandName, strings.ToLower(name)) {
+				completions = append(completions, commandName)
+			}
+		}
+		return completions
+	}
+	name, _ = splitCommand(name)
+	for _, candidate := range argumentCandidates(name, strings.TrimLeft(argument, " ")) {
+		completions = append(completions, name+" "+candidate)
+	}
+	return completions
+}
+
+func (line *commandLine) complete(offset int) {
+	if line.completions == nil {
+		line.completions = completeCommand(line.input)
+		line.completion = -1
+		if len(line.completions) == 0 {
+			line.completions = nil
+			return
+		}
+		if len(line.completions) == 1 {
+			line.input = line.completions[0]
+			line.completions = nil
+			return
+		}
+		if common := commonPrefix(line.completions); len(common) > len(line.input) {
+			line.input = common
+			return
+		}
+	}
+	line.completion = (line.completion + offset + len(line.completions) + 1) % (len(line.completions) + 1)
+	if line.completion == len(line.completions) {
+		line.completion = -1
+		line.input = commonPrefix(line.completions)
+		return
+	}
+	line.input = line.completions[line.completion]
+}
+
+func (line *commandLine) draw(screen tcell.Screen) {
+	width, height := screen.Size()
+	defaultStyle := tcell.StyleDefault.Foreground(tcell.ColorWhite)
+	for x := 0; x < width; x++ {
+		screen.SetContent(x, height-1, ' ', nil, defaultStyle)
+	}
+	input := truncateLeft(line.input, width-2)
+	drawText(screen, 0, height-1, ":"+input, defaultStyle)
+	screen.SetContent(1+len([]rune(input)), height-1, '_', nil, defaultStyle)
+
+	if len(line.completions) < 2 {
+		name, _ := splitCommand(line.input)
+		for _, command := range commands {
+			if command.name == name {
+				hint := strings.TrimSpace(":"+command.name+" "+command.arguments) + "  " + command.description
+				drawText(screen, 0, height-2, truncateRight(hint, width), tcell.StyleDefault.Foreground(tcell.ColorGray))
+			}
+		}
+		return
+	}
+	for x := 0; x < width; x++ {
+		screen.SetContent(x, height-2, ' ', nil, defaultStyle.Reverse(true))
+	}
+	x := 0
+	for index, completion := range line.completions {
+		_, label, hasArgument := strings.Cut(completion, " ")
+		if !hasArgument {
+			label = completion
+		}
+		if strings.HasSuffix(label, string(filepath.Separator)) {
+			label = filepath.Base(label) + string(filepath.Separator)
+		} else if strings.ContainsRune(label, filepath.Separator) {
+			label = filepath.Base(label)
+		}
+		style := defaultStyle.Reverse(true)
+		if index == line.completion {
+			style = defaultStyle.Bold(true)
+		}
+		if x+len([]rune(label)) > width {
+			break
+		}
+		drawText(screen, x, height-2, label, style)
+		x += len([]rune(label)) + 2
+	}
+}
+
+func (line *commandLine) handleEvent(event tcell.Event) bool {
+	keyEvent, ok := event.(*tcell.EventKey)
+	if !ok {
+		return false
+	}
+	if keyEvent.Key() != tcell.KeyTab && keyEvent.Key() != tcell.KeyBacktab {
+		line.completions = nil
+	}
+	switch keyEvent.Key() {
+	case tcell.KeyEscape:
+		return true
+	case tcell.KeyEnter:
+		if strings.TrimSpace(line.input) != "" {
+			if len(commandHistory) == 0 || commandHistory[len(commandHistory)-1] != line.input {
+				commandHistory = append(commandHistory, line.input)
+			}
+			if len(commandHistory) > maxCommandHistory {
+				commandHistory = commandHistory[len(commandHistory)-maxCommandHistory:]
+			}
+			line.accepted = true
+		}
+		return true
+	case tcell.KeyTab:
+		line.complete(1)
+	case tcell.KeyBacktab:
+		line.complete(-1)
+	case tcell.KeyUp:
+		if line.history > 0 {
+			if line.history == len(commandHistory) {
+				line.draft = line.input
+			}
+			line.history--
+			line.input = commandHistory[line.history]
+		}
+	case tcell.KeyDown:
+		if line.history < len(commandHistory) {
+			line.history++
+			if line.history == len(commandHistory) {
+				line.input = line.draft
+			} else {
+				line.input = commandHistory[line.history]
+			}
+		}
+	case tcell.KeyCtrlU:
+		line.input = ""
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		if line.input == "" {
+			return true
+		}
+		letters := []rune(line.input)
+		line.input = string(letters[:len(letters)-1])
+	case tcell.KeyRune:
+		line.input += string(keyEvent.Rune())
+	}
+	return false
+}
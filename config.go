@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type configuration struct {
+	Palette          string              `json:"palette"`
+	PrimaryColor     string              `json:"primaryColor"`
+	SecondaryColor   string              `json:"secondaryColor"`
+	Brush            string              `json:"brush"`
+	Tool             string              `json:"tool"`
+	Port             int                 `json:"port"`
+	Nickname         string              `json:"nickname"`
+	Servers          map[string]string   `json:"servers"`
+	AutosaveInterval string              `json:"autosaveInterval"`
+	KeyBindings      map[string][]string `json:"keybindings"`
+}
+
+var (
+	configFile       string
+	nickname         string
+	servers          = map[string]string{}
+	autosaveInterval = time.Minute
+)
+
+func configDirectory() (string, error) {
+	if directory := os.Getenv("XDG_CONFIG_HOME"); directory != "" {
+		return directory, nil
+	}
+	homeDirectory, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDirectory, ".config"), nil
+}
+
+func configPath() (string, error) {
+	if configFile != "" {
+		return configFile, nil
+	}
+	directory, err := configDirectory()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(directory, "termcanvas", "config.json"), nil
+}
+
+func loadConfig() (configuration, error) {
+	var loadedConfig configuration
+	filePath, err := configPath()
+	if err != nil {
+		return loadedConfig, nil
+	}
+	fileData, err := os.ReadFile(filePath)
+	if errors.Is(err, os.ErrNotExist) && configFile == "" {
+		return loadedConfig, nil
+	} else if err != nil {
+		return loadedConfig, err
+	}
+	if err := json.Unmarshal(fileData, &loadedConfig); err != nil {
+		return loadedConfig, fmt.Errorf("%v: %v", filePath, err.Error())
+	}
+	return loadedConfig, nil
+}
+
+func applyConfig(loadedConfig configuration) error {
+	setFlags := make(map[string]bool)
+	flag.Visit(func(setFlag *flag.Flag) {
+		setFlags[setFlag.Name] = true
+	})
+
+	if loadedConfig.Palette != "" && !setFlags["palette"] {
+		paletteFile = loadedConfig.Palette
+	}
+	if loadedConfig.PrimaryColor != "" && !setFlags["color"] {
+		primaryColor = loadedConfig.PrimaryColor
+	}
+	if loadedConfig.SecondaryColor != "" && !setFlags["secondary-color"] {
+		secondaryColor = loadedConfig.SecondaryColor
+	}
+	if loadedConfig.Brush != "" && !setFlags["brush"] {
+		brushName = loadedConfig.Brush
+	}
+	if loadedConfig.Tool != "" && !setFlags["tool"] {
+		selectedTool = loadedConfig.Tool
+	}
+	if loadedConfig.Port != 0 && !setFlags["port"] {
+		port = loadedConfig.Port
+	}
+	if loadedConfig.Nickname != "" && !setFlags["nickname"] {
+		nickname = loadedConfig.Nickname
+	}
+	if loadedConfig.AutosaveInterval != "" && !setFlags["autosave"] {
+		interval, err := time.ParseDuration(loadedConfig.AutosaveInterval)
+		if err != nil {
+			return fmt.Errorf("invalid autosave interval: %v", err.Error())
+		}
+		autosaveInterval = interval
+	}
+	for name, address := range loadedConfig.Servers {
+		servers[name] = address
+	}
+	if err := applyKeyBindings(loadedConfig.KeyBindings); err != nil {
+		return err
+	}
+
+	var ok bool
+	if primaryColor, ok = parseColorName(primaryColor); !ok {
+		return fmt.Errorf("unknown color %q", primaryColor)
+	}
+	if secondaryColor, ok = parseColorName(secondaryColor); !ok {
+		return fmt.Errorf("unknown secondary color %q", secondaryColor)
+	}
+	if brush, ok = parseGlyph(brushName); !ok {
+		return fmt.Errorf("invalid brush %q", brushName)
+	}
+	tool, ok := toolName(selectedTool)
+	if !ok {
+		return fmt.Errorf("unknown tool %q", selectedTool)
+	}
+	selectedTool = tool
+	if err := validateNickname(nickname); err != nil {
+		return err
+	}
+	if autosaveInterval < 0 {
+		return fmt.Errorf("invalid autosave interval: %v", autosaveInterval)
+	}
+	return nil
+}
+
+func toolName(name string) (string, bool) {
+	for _, tool := range tools {
+		if strings.EqualFold(tool, name) {
+			return tool, true
+		}
+	}
+	return name, false
+}
+
+func serverAddress(address string) string {
+	if serverAddress, ok := servers[address]; ok {
+		address = serverAddress
+	}
+	if _, _, err := net.SplitHostPort(address); err == nil {
+		return address
+	}
+	return net.JoinHostPort(address, strconv.Itoa(port))
+}
+
+func effectiveConfig() configuration {
+	return configuration{
+		Palette:          paletteFile,
+		PrimaryColor:     primaryColor,
+		SecondaryColor:   secondaryColor,
+		Brush:            string(brush),
+		Tool:             strings.ToLower(selectedTool),
+		Port:             port,
+		Nickname:         nickname,
+		Servers:          servers,
+		AutosaveInterval: autosaveInterval.String(),
+		KeyBindings:      keyBindings,
+	}
+}
+
+func printConfig() error {
+	data, err := json.MarshalIndent(effectiveConfig(), "", "\t")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+func validateNickname(name string) error {
+	if strings.ContainsAny(name, ",\n") {
+		return fmt.Errorf("nicknames can't contain commas or newlines")
+	}
+	return nil
+}
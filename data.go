@@ -2,26 +2,34 @@ package main
 
 import (
 	"fmt"
-	"os"
 	"strconv"
 	"strings"
 
 	"github.com/gdamore/tcell/v2"
 )
 
-func getColor(style tcell.Style) (string, string) {
-	foregroundColor, backgroundColor, _ := style.Decompose()
-	var foregroundColorName, backgroundColorName string
-	for _, existingColor := range colors {
-		if tcell.GetColor(existingColor) == foregroundColor {
-			foregroundColorName = existingColor
+const (
+	dataHeader      = "x,y,foregroundColor,backgroundColor,attributes,character\n"
+	layersHeader    = "layer,visible,locked\n"
+	layerDataHeader = "layer," + dataHeader
+)
+
+func getColorName(color tcell.Color) string {
+	for _, existingColor := range defaultColors {
+		if tcell.GetColor(existingColor) == color {
+			return existingColor
 		}
 	}
-	for _, existingColor := range colors {
-		if tcell.GetColor(existingColor) == backgroundColor {
-			backgroundColorName = existingColor
-		}
+	if color.Hex() != -1 {
+		return fmt.Sprintf("#%06X", color.Hex())
 	}
+	return ""
+}
+
+func getColor(style tcell.Style) (string, string) {
+	foregroundColor, backgroundColor, _ := style.Decompose()
+	foregroundColorName := getColorName(foregroundColor)
+	backgroundColorName := getColorName(backgroundColor)
 	if foregroundColorName == "" && backgroundColorName == "" {
 		return "", ""
 	}
@@ -34,56 +42,232 @@ func getColor(style tcell.Style) (string, string) {
 	return foregroundColorName, backgroundColorName
 }
 
-func dumpData(screen tcell.Screen) (string, bool) {
-	data := "x,y,foregroundColor,backgroundColor,character\n"
+func formatAttributes(attributeMask tcell.AttrMask) string {
+	formattedAttributes := ""
+	for index, attribute := range attributes {
+		if attributeMask&attribute != 0 {
+			formattedAttributes += string(attributeLetters[index])
+		}
+	}
+	return formattedAttributes
+}
+
+func parseAttributes(formattedAttributes string) tcell.AttrMask {
+	attributeMask := tcell.AttrNone
+	for index, attribute := range attributes {
+		if strings.ContainsRune(formattedAttributes, rune(attributeLetters[index])) {
+			attributeMask |= attribute
+		}
+	}
+	return attributeMask
+}
+
+func parseCell(line string, segments []string, hasAttributes bool) (rune, tcell.Style) {
+	characterIndex := 4
+	attributeMask := tcell.AttrNone
+	if hasAttributes {
+		characterIndex = 5
+		attributeMask = parseAttributes(segments[4])
+	}
+	character := ' '
+	if hasAttributes {
+		if len(segments) > characterIndex+1 {
+			character = ','
+		} else if len(segments) > characterIndex {
+			characters := []rune(segments[characterIndex])
+			character = 0
+			if len(characters) > 0 {
+				character = characters[0]
+			}
+		}
+	} else if strings.HasSuffix(line, ",,") {
+		character = ','
+	} else if len(segments) > characterIndex {
+		characters := []rune(segments[characterIndex])
+		if len(characters) > 0 {
+			character = characters[0]
+		}
+	}
+	style := tcell.StyleDefault.
+		Foreground(tcell.GetColor(segments[2])).
+		Background(tcell.GetColor(segments[3])).
+		Attributes(attributeMask)
+	return character, style
+}
+
+func formatStyle(style tcell.Style) (string, string, string) {
+	foregroundColorName, backgroundColorName := getColor(style)
+	if foregroundColorName == "" && backgroundColorName == "" {
+		foregroundColorName = "reset"
+		backgroundColorName = "reset"
+	}
+	_, _, attributeMask := style.Decompose()
+	return foregroundColorName, backgroundColorName, formatAttributes(attributeMask)
+}
+
+func formatCell(x, y int, character rune, style tcell.Style) string {
+	formattedCharacter := string(character)
+	if character == 0 {
+		formattedCharacter = ""
+	}
+	foregroundColorName, backgroundColorName := getColor(style)
+	_, _, attributeMask := style.Decompose()
+	if foregroundColorName == "" && backgroundColorName == "" {
+		if attributeMask == tcell.AttrNone {
+			return ""
+		}
+		foregroundColorName = "reset"
+		backgroundColorName = "reset"
+	}
+	return fmt.Sprintf(
+		"%v,%v,%v,%v,%v,%v\n",
+		x,
+		y,
+		foregroundColorName,
+		backgroundColorName,
+		formatAttributes(attributeMask),
+		formattedCharacter,
+	)
+}
+
+func dumpData() (string, bool) {
+	layers := canvas.snapshot()
+	data := layersHeader
+	for _, existingLayer := range layers {
+		data += fmt.Sprintf("%v,%v,%v\n", existingLayer.name, existingLayer.visible, existingLayer.locked)
+	}
+	data += "\n" + layerDataHeader
 	empty := true
-	width, height := screen.Size()
-	for x := 0; x <= width; x++ {
-		for y := 4; y <= height; y++ {
-			character, _, style, _ := screen.GetContent(x, y)
-			if character != ' ' && character != 0 {
+	for _, existingLayer := range layers {
+		for _, cellPoint := range existingLayer.points() {
+			existingCell := existingLayer.cells[cellPoint]
+			if existingCell.character != ' ' && existingCell.character != 0 {
 				empty = false
 			}
-			foregroundColorName, backgroundColorName := getColor(style)
-			if foregroundColorName == "" && backgroundColorName == "" {
-				continue
+			if formattedCell := formatCell(cellPoint.x, cellPoint.y, existingCell.character, existingCell.style); formattedCell != "" {
+				data += existingLayer.name + "," + formattedCell
 			}
-			data += fmt.Sprintf("%v,%v,%v,%v,%v\n", x, y, foregroundColorName, backgroundColorName, string(character))
 		}
 	}
 	return data, empty
 }
 
-func drawData(data string, screen tcell.Screen) {
-	for index, line := range strings.Split(data, "\n") {
+func formatCells(cells map[point]cell) string {
+	data := dataHeader
+	for _, cellPoint := range sortedCellPoints(cells) {
+		data += formatCell(cellPoint.x, cellPoint.y, cells[cellPoint].character, cells[cellPoint].style)
+	}
+	return data
+}
+
+func parseCells(data string) (map[point]cell, error) {
+	lines := strings.Split(data, "\n")
+	hasAttributes := strings.Contains(lines[0], "attributes")
+	cells := make(map[point]cell)
+	for index, line := range lines {
 		if index == 0 || strings.TrimSpace(line) == "" {
 			continue
 		}
 		segments := strings.Split(line, ",")
+		if len(segments) < 4 || (hasAttributes && len(segments) < 5) {
+			return nil, fmt.Errorf("invalid cell at line %v", index+1)
+		}
 		x, err := strconv.Atoi(segments[0])
 		if err != nil {
-			screen.Fini()
-			fmt.Printf("Invalid X coordinate at line %v\n", index+1)
-			os.Exit(1)
+			return nil, fmt.Errorf("invalid X coordinate at line %v", index+1)
 		}
 		y, err := strconv.Atoi(segments[1])
 		if err != nil {
-			screen.Fini()
-			fmt.Printf("Invalid Y coordinate at line %v\n", index+1)
-			os.Exit(1)
+			return nil, fmt.Errorf("invalid Y coordinate at line %v", index+1)
 		}
-		character := ' '
-		if strings.HasSuffix(line, ",,") {
-			character = ','
-		} else {
-			characters := []rune(segments[4])
-			if len(characters) > 0 {
-				character = characters[0]
-			}
+		character, style := parseCell(line, segments, hasAttributes)
+		cells[point{x, y}] = cell{character, style}
+	}
+	return cells, nil
+}
+
+func parseLayerData(data string) ([]layer, error) {
+	lines := strings.Split(data, "\n")
+	var layers []layer
+	layerIndexes := make(map[string]int)
+	index := 1
+	for ; index < len(lines) && strings.TrimSpace(lines[index]) != ""; index++ {
+		segments := strings.Split(lines[index], ",")
+		if len(segments) < 3 {
+			return nil, fmt.Errorf("invalid layer at line %v", index+1)
+		}
+		layerIndexes[segments[0]] = len(layers)
+		layers = append(layers, layer{
+			name:    segments[0],
+			visible: segments[1] == "true",
+			locked:  segments[2] == "true",
+			cells:   make(map[point]cell),
+		})
+	}
+	for index += 2; index < len(lines); index++ {
+		line := lines[index]
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		segments := strings.Split(line, ",")
+		if len(segments) < 6 {
+			return nil, fmt.Errorf("invalid cell at line %v", index+1)
+		}
+		x, err := strconv.Atoi(segments[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid X coordinate at line %v", index+1)
+		}
+		y, err := strconv.Atoi(segments[2])
+		if err != nil {
+			return nil, fmt.Errorf("invalid Y coordinate at line %v", index+1)
 		}
-		textColor := tcell.StyleDefault.
-			Foreground(tcell.GetColor(segments[2])).
-			Background(tcell.GetColor(segments[3]))
-		screen.SetContent(x, y, character, nil, textColor)
+		layerIndex, ok := layerIndexes[segments[0]]
+		if !ok {
+			layerIndex = len(layers)
+			layerIndexes[segments[0]] = layerIndex
+			layers = append(layers, layer{name: segments[0], visible: true, cells: make(map[point]cell)})
+		}
+		character, style := parseCell(line, segments[1:], true)
+		layers[layerIndex].cells[point{x, y}] = cell{character, style}
+	}
+	return layers, nil
+}
+
+func parseCanvasData(data string) ([]layer, bool, error) {
+	if strings.HasPrefix(data, layersHeader) {
+		layers, err := parseLayerData(data)
+		return layers, true, err
+	}
+	cells, err := parseCells(data)
+	if err != nil {
+		return nil, false, err
+	}
+	return []layer{{visible: true, cells: cells}}, false, nil
+}
+
+func drawData(data string, screen tcell.Screen) error {
+	layers, layered, err := parseCanvasData(data)
+	if err != nil {
+		return err
+	}
+	if !layered {
+		for _, cellPoint := range layers[0].points() {
+			existingCell := layers[0].cells[cellPoint]
+			setContent(screen, cellPoint.x, cellPoint.y, existingCell.character, existingCell.style, false)
+		}
+		return nil
+	}
+
+	defer canvas.clearTarget()
+	for _, loadedLayer := range layers {
+		canvas.setTarget(loadedLayer.name)
+		for _, cellPoint := range loadedLayer.points() {
+			existingCell := loadedLayer.cells[cellPoint]
+			setContent(screen, cellPoint.x, cellPoint.y, existingCell.character, existingCell.style, false)
+		}
+	}
+	for index, loadedLayer := range layers {
+		canvas.updateLayer(loadedLayer.name, index, loadedLayer.visible, loadedLayer.locked)
 	}
+	return nil
 }
@@ -0,0 +1,15 @@
+package main
+
+import "testing"
+
+func TestParseTruncatedCells(t *testing.T) {
+	if _, err := parseCells(dataHeader + "1,5,red,blue\n"); err == nil || err.Error() != "invalid cell at line 2" {
+		t.Errorf("got %v for a cell without attributes", err)
+	}
+	if _, err := parseCells("x,y,foregroundColor,backgroundColor,character\n1,5,red,blue\n"); err != nil {
+		t.Errorf("got %v for a cell from an old file", err)
+	}
+	if _, err := parseLayerData(layersHeader + "Layer 1,true,false\n\n" + layerDataHeader + "Layer 1,1,5,red,blue\n"); err == nil || err.Error() != "invalid cell at line 5" {
+		t.Errorf("got %v for a layer cell without attributes", err)
+	}
+}
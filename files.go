@@ -0,0 +1,722 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+)
+
+const (
+	fileBrowserWidth    = 80
+	fileBrowserHeight   = 24
+	maxPreviewFileSize  = 4 << 20
+	fileBrowserOkLabel  = "[ OK ]"
+	fileBrowserNoLabel  = "[ Cancel ]"
+	fileBrowserParent   = ".."
+	fileBrowserNoSample = "No preview"
+)
+
+type fileFormat struct {
+	name      string
+	extension string
+}
+
+type fileEntry struct {
+	name      string
+	directory bool
+}
+
+type fileBrowser struct {
+	title       string
+	directory   string
+	input       string
+	entries     []fileEntry
+	selected    int
+	scroll      int
+	formats     []fileFormat
+	format      int
+	saving      bool
+	emptyHint   string
+	confirming  bool
+	message     string
+	accepted    bool
+	filePath    string
+	onAccept    func(filePath string, format fileFormat)
+	previewPath string
+	preview     map[point]cell
+	x, y        int
+	width       int
+	height      int
+}
+
+var (
+	saveFormats = []fileFormat{
+		{"termcanvas CSV", ".csv"},
+		{"Plain text", ".txt"},
+		{"ANSI art", ".ans"},
+	}
+	loadFormats = []fileFormat{
+		{"termcanvas CSV", ".csv"},
+		{"Plain text", ".txt"},
+	}
+	lastFilePath  string
+	savedRevision int
+)
+
+func newFileBrowser(title, filePath string, formats []fileFormat, saving bool, onAccept func(string, fileFormat)) *fileBrowser {
+	browser := &fileBrowser{
+		title:    title,
+		formats:  formats,
+		saving:   saving,
+		selected: -1,
+		onAccept: onAccept,
+	}
+	browser.directory, _ = os.Getwd()
+	if filePath != "" {
+		if absolutePath, err := filepath.Abs(filePath); err == nil {
+			browser.directory = filepath.Dir(absolutePath)
+			if saving {
+				browser.input = filepath.Base(absolutePath)
+			}
+			browser.selectFormat(absolutePath)
+		}
+	}
+	browser.readDirectory()
+	return browser
+}
+
+func (browser *fileBrowser) readDirectory() {
+	browser.entries = nil
+	browser.selected = -1
+	browser.scroll = 0
+	if filepath.Dir(browser.directory) != browser.directory {
+		browser.entries = append(browser.entries, fileEntry{fileBrowserParent, true})
+	}
+	directoryEntries, err := os.ReadDir(browser.directory)
+	if err != nil {
+		browser.message = "Unable to read directory: " + err.Error()
+		return
+	}
+	var entries []fileEntry
+	for _, directoryEntry := range directoryEntries {
+		if strings.HasPrefix(directoryEntry.Name(), ".") {
+			continue
+		}
+		directory := directoryEntry.IsDir()
+		if !directory && directoryEntry.Type()&os.ModeSymlink != 0 {
+			if info, err := os.Stat(filepath.Join(browser.directory, directoryEntry.Name())); err == nil {
+				directory = info.IsDir()
+			}
+		}
+		entries = append(entries, fileEntry{directoryEntry.Name(), directory})
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].directory && !entries[j].directory
+	})
+	browser.entries = append(browser.entries, entries...)
+}
+
+func (browser *fileBrowser) navigate(directory string) {
+	browser.directory = filepath.Clean(directory)
+	browser.input = ""
+	browser.message = ""
+	browser.readDirectory()
+}
+
+func (browser *fileBrowser) resolve(input string) string {
+	return resolvePath(browser.directory, input)
+}
+
+func resolvePath(directory, input string) string {
+	if input == "~" || strings.HasPrefix(input, "~/") {
+		if homeDirectory, err := os.UserHomeDir(); err == nil {
+			input = filepath.Join(homeDirectory, input[1:])
+		}
+	}
+	if !filepath.IsAbs(input) {
+		input = filepath.Join(directory, input)
+	}
+	return input
+}
+
+func (browser *fileBrowser) selectFormat(filePath string) {
+	extension := strings.ToLower(filepath.Ext(filePath))
+	for index, format := range browser.formats {
+		if format.extension == extension {
+			browser.format = index
+		}
+	}
+}
+
+func (browser *fileBrowser) cycleFormat(offset int) {
+	if len(browser.formats) == 0 {
+		return
+	}
+	oldExtension := browser.formats[browser.format].extension
+	browser.format = (browser.format + offset + len(browser.formats)) % len(browser.formats)
+	if strings.HasSuffix(strings.ToLower(browser.input), oldExtension) {
+		browser.input = browser.input[:len(browser.input)-len(oldExtension)] + browser.formats[browser.format].extension
+	}
+}
+
+func (browser *fileBrowser) selectEntry(index int) {
+	if index < 0 || index >= len(browser.entries) {
+		return
+	}
+	browser.selected = index
+	browser.message = ""
+	entry := browser.entries[index]
+	browser.input = entry.name
+	if entry.directory {
+		browser.input += string(filepath.Separator)
+	} else {
+		browser.selectFormat(entry.name)
+	}
+}
+
+func completePath(directory, input string) ([]string, error) {
+	directoryPart, prefix := filepath.Split(input)
+	searchDirectory := directory
+	if directoryPart != "" {
+		searchDirectory = resolvePath(directory, directoryPart)
+	}
+	directoryEntries, err := os.ReadDir(searchDirectory)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []string
+	for _, directoryEntry := range directoryEntries {
+		name := directoryEntry.Name()
+		if !strings.HasPrefix(name, prefix) || strings.HasPrefix(name, ".") && !strings.HasPrefix(prefix, ".") {
+			continue
+		}
+		if info, err := os.Stat(filepath.Join(searchDirectory, name)); err == nil && info.IsDir() {
+			name += string(filepath.Separator)
+		}
+		matches = append(matches, directoryPart+name)
+	}
+	return matches, nil
+}
+
+func commonPrefix(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	common := []rune(values[0])
+	for _, value := range values[1:] {
+		for !strings.HasPrefix(value, string(common)) {
+			common = common[:len(common)-1]
+		}
+	}
+	return string(common)
+}
+
+func (browser *fileBrowser) complete() {
+	matches, err := completePath(browser.directory, browser.input)
+	if err != nil {
+		browser.message = "Unable to read directory: " + err.Error()
+		return
+	}
+	if len(matches) == 0 {
+		browser.message = "No matches"
+		return
+	}
+	if len(matches) == 1 {
+		if strings.HasSuffix(matches[0], string(filepath.Separator)) {
+			browser.navigate(browser.resolve(matches[0]))
+			return
+		}
+		browser.input = matches[0]
+		browser.selectFormat(matches[0])
+		browser.message = ""
+		return
+	}
+	browser.input = commonPrefix(matches)
+	names := make([]string, len(matches))
+	for index, match := range matches {
+		names[index] = filepath.Base(match)
+	}
+	browser.message = strings.Join(names, "  ")
+}
+
+func (browser *fileBrowser) accept() bool {
+	if browser.input == "" {
+		if browser.emptyHint != "" {
+			browser.accepted = true
+			return true
+		}
+		browser.message = "Type a file name or pick a file"
+		return false
+	}
+
+	filePath := browser.resolve(browser.input)
+	info, err := os.Stat(filePath)
+	if err == nil && info.IsDir() {
+		browser.navigate(filePath)
+		return false
+	}
+	if !browser.saving {
+		if err != nil {
+			browser.message = "Unable to open " + filepath.Base(filePath) + ": " + err.Error()
+			return false
+		}
+	} else {
+		if filepath.Ext(filePath) == "" && len(browser.formats) > 0 {
+			filePath += browser.formats[browser.format].extension
+		}
+		if _, err := os.Stat(filePath); err == nil && !browser.confirming {
+			browser.confirming = true
+			browser.message = filepath.Base(filePath) + " already exists, overwrite it? [Y]es/[N]o"
+			return false
+		}
+	}
+	browser.filePath = filePath
+	browser.accepted = true
+	return true
+}
+
+func (browser *fileBrowser) selectedFormat() fileFormat {
+	if len(browser.formats) == 0 {
+		return fileFormat{}
+	}
+	return browser.formats[browser.format]
+}
+
+func (browser *fileBrowser) listHeight() int {
+	return browser.height - 7
+}
+
+func (browser *fileBrowser) listWidth() int {
+	if browser.width >= 60 {
+		return (browser.width - 5) / 2
+	}
+	return browser.width - 4
+}
+
+func (browser *fileBrowser) updatePreview() {
+	previewPath := ""
+	if browser.input != "" {
+		previewPath = browser.resolve(browser.input)
+	}
+	if previewPath == browser.previewPath {
+		return
+	}
+	browser.previewPath = previewPath
+	browser.preview = nil
+	if previewPath == "" {
+		return
+	}
+	info, err := os.Stat(previewPath)
+	if err != nil || info.IsDir() || info.Size() > maxPreviewFileSize {
+		return
+	}
+	fileData, err := os.ReadFile(previewPath)
+	if err != nil {
+		return
+	}
+	browser.preview = previewCells(previewPath, string(fileData))
+}
+
+func previewCells(filePath, data string) map[point]cell {
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".txt":
+		return parseText(data, tcell.StyleDefault.Foreground(tcell.GetColor(primaryColor)))
+	case ".csv":
+		layers, _, err := parseCanvasData(data)
+		if err != nil {
+			return nil
+		}
+		return compositeCells(layers)
+	}
+	return nil
+}
+
+func drawThumbnail(screen tcell.Screen, x, y, width, height int, cells map[point]cell) bool {
+	var minimum, maximum point
+	first := true
+	for cellPoint, existingCell := range cells {
+		if existingCell.character == 0 || transparentCell(existingCell.character, existingCell.style) {
+			continue
+		}
+		if first {
+			minimum, maximum = cellPoint, cellPoint
+			first = false
+			continue
+		}
+		if cellPoint.x < minimum.x {
+			minimum.x = cellPoint.x
+		}
+		if cellPoint.y < minimum.y {
+			minimum.y = cellPoint.y
+		}
+		if cellPoint.x > maximum.x {
+			maximum.x = cellPoint.x
+		}
+		if cellPoint.y > maximum.y {
+			maximum.y = cellPoint.y
+		}
+	}
+	if first || width <= 0 || height <= 0 {
+		return false
+	}
+
+	scaleX := (maximum.x - minimum.x + width) / width
+	scaleY := (maximum.y - minimum.y + height) / height
+	colorCount := screen.Colors()
+	for row := 0; row < height; row++ {
+		for col := 0; col < width; col++ {
+			sampledCell, found := cell{}, false
+			for offsetY := 0; offsetY < scaleY && !found; offsetY++ {
+				for offsetX := 0; offsetX < scaleX && !found; offsetX++ {
+					existingCell, ok := cells[point{minimum.x + col*scaleX + offsetX, minimum.y + row*scaleY + offsetY}]
+					if ok && existingCell.character != 0 && !transparentCell(existingCell.character, existingCell.style) {
+						sampledCell, found = existingCell, true
+					}
+				}
+			}
+			if !found {
+				continue
+			}
+			character := sampledCell.character
+			if runewidth.RuneWidth(character) != 1 {
+				character = block
+			}
+			screen.SetContent(x+col, y+row, character, nil, adaptStyle(sampledCell.style, colorCount))
+		}
+	}
+	return true
+}
+
+func (browser *fileBrowser) draw(screen tcell.Screen) {
+	screenWidth, screenHeight := screen.Size()
+	browser.width, browser.height = fileBrowserWidth, fileBrowserHeight
+	if browser.width > screenWidth-2 {
+		browser.width = screenWidth - 2
+	}
+	if browser.height > screenHeight-5 {
+		browser.height = screenHeight - 5
+	}
+	if browser.width < 40 {
+		browser.width = 40
+	}
+	if browser.height < 12 {
+		browser.height = 12
+	}
+	browser.x, browser.y = centeredBox(screen, browser.width, browser.height)
+	x, y := browser.x, browser.y
+
+	defaultStyle := tcell.StyleDefault.Foreground(tcell.ColorWhite)
+	grayStyle := tcell.StyleDefault.Foreground(tcell.ColorGray)
+	drawBox(screen, x, y, x+browser.width-1, y+browser.height-1, browser.title, defaultStyle)
+	drawText(screen, x+2, y+1, truncateLeft(browser.directory, browser.width-4), grayStyle)
+
+	listWidth, listHeight := browser.listWidth(), browser.listHeight()
+	if browser.selected >= 0 {
+		if browser.selected < browser.scroll {
+			browser.scroll = browser.selected
+		} else if browser.selected >= browser.scroll+listHeight {
+			browser.scroll = browser.selected - listHeight + 1
+		}
+	}
+	for row := 0; row < listHeight && browser.scroll+row < len(browser.entries); row++ {
+		entry := browser.entries[browser.scroll+row]
+		name := entry.name
+		style := defaultStyle
+		if entry.directory {
+			name += string(filepath.Separator)
+			style = style.Bold(true)
+		}
+		if browser.scroll+row == browser.selected {
+			style = style.Reverse(true)
+		}
+		drawText(screen, x+2, y+2+row, truncateRight(name, listWidth), style)
+	}
+
+	if browser.width >= 60 {
+		previewX := x + 3 + listWidth
+		previewWidth := browser.width - 5 - listWidth
+		drawBox(screen, previewX, y+2, previewX+previewWidth-1, y+1+listHeight, "Preview", grayStyle)
+		browser.updatePreview()
+		if !drawThumbnail(screen, previewX+1, y+3, previewWidth-2, listHeight-2, browser.preview) {
+			drawText(screen, previewX+(previewWidth-len(fileBrowserNoSample))/2, y+1+(listHeight+1)/2, fileBrowserNoSample, grayStyle)
+		}
+	}
+
+	message := browser.message
+	if message == "" {
+		message = browser.emptyHint
+	}
+	drawText(screen, x+2, y+browser.height-5, truncateRight(message, browser.width-4), grayStyle)
+	nameLabel := "Name: "
+	drawText(screen, x+2, y+browser.height-4, nameLabel, defaultStyle)
+	input := truncateLeft(browser.input, browser.width-len(nameLabel)-5)
+	drawText(screen, x+2+len(nameLabel), y+browser.height-4, input, defaultStyle)
+	screen.SetContent(x+2+len(nameLabel)+len([]rune(input)), y+browser.height-4, '_', nil, defaultStyle)
+	if len(browser.formats) > 0 {
+		drawText(screen, x+2, y+browser.height-3, "Format: ◀ "+browser.formats[browser.format].name+" ▶", defaultStyle)
+	}
+	drawText(screen, x+2, y+browser.height-2, fileBrowserOkLabel, defaultStyle)
+	drawText(screen, x+3+len(fileBrowserOkLabel), y+browser.height-2, fileBrowserNoLabel, defaultStyle)
+}
+
+func truncateLeft(text string, width int) string {
+	letters := []rune(text)
+	if width < 1 {
+		return ""
+	}
+	if len(letters) > width {
+		return "…" + string(letters[len(letters)-width+1:])
+	}
+	return text
+}
+
+func truncateRight(text string, width int) string {
+	letters := []rune(text)
+	if width < 1 {
+		return ""
+	}
+	if len(letters) > width {
+		return string(letters[:width-1]) + "…"
+	}
+	return text
+}
+
+func (browser *fileBrowser) handleEvent(event tcell.Event) bool {
+	switch event := event.(type) {
+	case *tcell.EventKey:
+		if browser.confirming {
+			switch {
+			case event.Key() == tcell.KeyEnter || event.Key() == tcell.KeyRune && strings.ToLower(string(event.Rune())) == "y":
+				return browser.accept()
+			case event.Key() == tcell.KeyEscape || event.Key() == tcell.KeyRune && strings.ToLower(string(event.Rune())) == "n":
+				browser.confirming = false
+				browser.message = ""
+			}
+			return false
+		}
+		switch event.Key() {
+		case tcell.KeyEscape:
+			return true
+		case tcell.KeyEnter:
+			return browser.accept()
+		case tcell.KeyTab:
+			browser.complete()
+		case tcell.KeyUp:
+			if browser.selected > 0 {
+				browser.selectEntry(browser.selected - 1)
+			} else {
+				browser.selectEntry(0)
+			}
+		case tcell.KeyDown:
+			browser.selectEntry(browser.selected + 1)
+		case tcell.KeyPgUp:
+			browser.selectEntry(maxInt(browser.selected-browser.listHeight(), 0))
+		case tcell.KeyPgDn:
+			browser.selectEntry(minInt(browser.selected+browser.listHeight(), len(browser.entries)-1))
+		case tcell.KeyLeft:
+			browser.cycleFormat(-1)
+		case tcell.KeyRight:
+			browser.cycleFormat(1)
+		case tcell.KeyBackspace, tcell.KeyBackspace2:
+			if browser.input == "" {
+				browser.navigate(filepath.Dir(browser.directory))
+			} else {
+				letters := []rune(browser.input)
+				browser.input = string(letters[:len(letters)-1])
+				browser.selected = -1
+			}
+		case tcell.KeyRune:
+			browser.input += string(event.Rune())
+			browser.selected = -1
+			browser.message = ""
+		}
+	case *tcell.EventMouse:
+		x, y := event.Position()
+		switch event.Buttons() {
+		case tcell.WheelUp:
+			if browser.scroll > 0 {
+				browser.scroll--
+			}
+		case tcell.WheelDown:
+			if browser.scroll+browser.listHeight() < len(browser.entries) {
+				browser.scroll++
+			}
+		case tcell.Button1:
+			row := y - browser.y - 2
+			bottom := browser.y + browser.height
+			if row >= 0 && row < browser.listHeight() && x >= browser.x+2 && x < browser.x+2+browser.listWidth() {
+				index := browser.scroll + row
+				if index >= len(browser.entries) {
+					return false
+				}
+				if index == browser.selected {
+					if browser.entries[index].directory {
+						browser.navigate(filepath.Join(browser.directory, browser.entries[index].name))
+						return false
+					}
+					return browser.accept()
+				}
+				browser.selectEntry(index)
+			} else if y == bottom-3 && len(browser.formats) > 0 {
+				formatLabelEnd := browser.x + 2 + len("Format: ◀ ") + len([]rune(browser.formats[browser.format].name))
+				if x >= browser.x+2+len("Format: ") && x < browser.x+2+len("Format: ◀") {
+					browser.cycleFormat(-1)
+				} else if x >= formatLabelEnd && x <= formatLabelEnd+1 {
+					browser.cycleFormat(1)
+				}
+			} else if y == bottom-2 && x >= browser.x+2 && x < browser.x+2+len(fileBrowserOkLabel) {
+				return browser.accept()
+			} else if y == bottom-2 && x > browser.x+2+len(fileBrowserOkLabel) && x <= browser.x+2+len(fileBrowserOkLabel)+len(fileBrowserNoLabel) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func canvasBounds(cells map[point]cell) (point, point, bool) {
+	var minimum, maximum point
+	found := false
+	for cellPoint := range cells {
+		if !found {
+			minimum, maximum, found = cellPoint, cellPoint, true
+			continue
+		}
+		minimum.x, minimum.y = minInt(minimum.x, cellPoint.x), minInt(minimum.y, cellPoint.y)
+		maximum.x, maximum.y = maxInt(maximum.x, cellPoint.x), maxInt(maximum.y, cellPoint.y)
+	}
+	return minimum, maximum, found
+}
+
+func formatText(cells map[point]cell, ansi bool) string {
+	minimum, maximum, found := canvasBounds(cells)
+	if !found {
+		return ""
+	}
+	var builder strings.Builder
+	for y := minimum.y; y <= maximum.y; y++ {
+		var line strings.Builder
+		lastStyle := tcell.StyleDefault
+		for x := minimum.x; x <= maximum.x; x++ {
+			existingCell, ok := cells[point{x, y}]
+			if !ok {
+				existingCell = cell{' ', tcell.StyleDefault}
+			}
+			if existingCell.character == 0 {
+				continue
+			}
+			if ansi && existingCell.style != lastStyle {
+				line.WriteString(ansiStyle(existingCell.style))
+				lastStyle = existingCell.style
+			}
+			line.WriteRune(existingCell.character)
+		}
+		if ansi {
+			if lastStyle != tcell.StyleDefault {
+				line.WriteString("\x1b[0m")
+			}
+			builder.WriteString(line.String())
+		} else {
+			builder.WriteString(strings.TrimRight(line.String(), " "))
+		}
+		builder.WriteString("\n")
+	}
+	return builder.String()
+}
+
+func ansiColor(color tcell.Color, base int) string {
+	if color == tcell.ColorDefault || color == tcell.ColorReset {
+		return fmt.Sprint(base + 9)
+	}
+	red, green, blue := color.RGB()
+	return fmt.Sprintf("%v;2;%v;%v;%v", base+8, red, green, blue)
+}
+
+func ansiStyle(style tcell.Style) string {
+	foregroundColor, backgroundColor, attributeMask := style.Decompose()
+	codes := []string{"0"}
+	attributeCodes := map[tcell.AttrMask]string{
+		tcell.AttrBold:      "1",
+		tcell.AttrItalic:    "3",
+		tcell.AttrUnderline: "4",
+		tcell.AttrBlink:     "5",
+		tcell.AttrReverse:   "7",
+	}
+	for _, attribute := range attributes {
+		if attributeMask&attribute != 0 {
+			codes = append(codes, attributeCodes[attribute])
+		}
+	}
+	codes = append(codes, ansiColor(foregroundColor, 30), ansiColor(backgroundColor, 40))
+	return "\x1b[" + strings.Join(codes, ";") + "m"
+}
+
+func parseText(data string, style tcell.Style) map[point]cell {
+	cells := make(map[point]cell)
+	for y, line := range strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n") {
+		x := 0
+		for _, character := range line {
+			if character == '\t' {
+				x += 8 - x%8
+				continue
+			}
+			characterWidth := runewidth.RuneWidth(character)
+			if characterWidth == 0 {
+				continue
+			}
+			if character != ' ' {
+				cells[point{x, y}] = cell{character, style}
+				if characterWidth == 2 {
+					cells[point{x + 1, y}] = cell{0, style}
+				}
+			}
+			x += characterWidth
+		}
+	}
+	return cells
+}
+
+func saveCanvas(filePath string, format fileFormat) error {
+	var data string
+	switch format.extension {
+	case ".txt":
+		data = formatText(compositeCells(canvas.snapshot()), false)
+	case ".ans":
+		data = formatText(compositeCells(canvas.snapshot()), true)
+	default:
+		data, _ = dumpData()
+	}
+	return writeFileAtomic(filePath, []byte(data), 0644)
+}
+
+func loadCanvas(screen tcell.Screen, filePath string, format fileFormat) error {
+	fileData, err := os.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+	if format.extension == ".txt" {
+		pasteCells(screen, 0, toolbarHeight, parseText(string(fileData), tcell.StyleDefault.Foreground(tcell.GetColor(primaryColor))), false)
+		return nil
+	}
+	return drawData(string(fileData), screen)
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestTextRoundTrip(t *testing.T) {
+	cells := parseText("hi there\n\tx,y\n漢字", tcell.StyleDefault.Foreground(tcell.GetColor(primaryColor)))
+	data := formatCells(cells)
+	parsedCells, err := parseCells(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsedCells) != len(cells) {
+		t.Fatalf("got %v cells back from %q, want %v", len(parsedCells), data, len(cells))
+	}
+	for cellPoint, existingCell := range cells {
+		parsedCell, ok := parsedCells[cellPoint]
+		if !ok || parsedCell.character != existingCell.character {
+			t.Errorf("got %q at %v, want %q", parsedCell.character, cellPoint, existingCell.character)
+		}
+	}
+	if formatCells(parsedCells) != data {
+		t.Errorf("got %q after a round trip, want %q", formatCells(parsedCells), data)
+	}
+}
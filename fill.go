@@ -0,0 +1,204 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+const (
+	fillDialogWidth  = 40
+	fillDialogHeight = 7
+)
+
+var (
+	fillConnectivity = 4
+	fillMatch        = "both"
+	fillTolerance    = 0
+	fillMatchModes   = []string{"character", "color", "both"}
+)
+
+type fillDialog struct {
+	x, y int
+}
+
+func normalizeColor(color tcell.Color) tcell.Color {
+	if color == tcell.ColorReset {
+		return tcell.ColorDefault
+	}
+	return color
+}
+
+func colorsMatch(color1, color2 tcell.Color, tolerance int) bool {
+	color1, color2 = normalizeColor(color1), normalizeColor(color2)
+	if color1 == color2 {
+		return true
+	}
+	if tolerance == 0 || color1.Hex() == -1 || color2.Hex() == -1 {
+		return false
+	}
+	return toColorful(color1).DistanceCIEDE2000(toColorful(color2))*100 <= float64(tolerance)
+}
+
+func cellsMatch(character1 rune, style1 tcell.Style, character2 rune, style2 tcell.Style, match string, tolerance int) bool {
+	if match != "color" && character1 != character2 {
+		return false
+	}
+	if match != "character" {
+		foregroundColor1, backgroundColor1, _ := style1.Decompose()
+		foregroundColor2, backgroundColor2, _ := style2.Decompose()
+		if !colorsMatch(foregroundColor1, foregroundColor2, tolerance) ||
+			!colorsMatch(backgroundColor1, backgroundColor2, tolerance) {
+			return false
+		}
+	}
+	return true
+}
+
+func fillPoints(x, y, minX, minY, maxX, maxY, connectivity int, match string, tolerance int) []point {
+	if x < minX || x > maxX || y < minY || y > maxY {
+		return nil
+	}
+	targetCharacter, targetStyle, _ := canvas.get(x, y)
+	neighbors := []point{{1, 0}, {-1, 0}, {0, 1}, {0, -1}}
+	if connectivity == 8 {
+		neighbors = append(neighbors, point{1, 1}, point{1, -1}, point{-1, 1}, point{-1, -1})
+	}
+
+	visited := map[point]bool{{x, y}: true}
+	queue := []point{{x, y}}
+	points := []point{}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		points = append(points, current)
+		for _, neighbor := range neighbors {
+			next := point{current.x + neighbor.x, current.y + neighbor.y}
+			if next.x < minX || next.x > maxX || next.y < minY || next.y > maxY || visited[next] {
+				continue
+			}
+			visited[next] = true
+			character, style, _ := canvas.get(next.x, next.y)
+			if cellsMatch(character, style, targetCharacter, targetStyle, match, tolerance) {
+				queue = append(queue, next)
+			}
+		}
+	}
+	sort.Slice(points, func(i, j int) bool {
+		if points[i].y == points[j].y {
+			return points[i].x < points[j].x
+		}
+		return points[i].y < points[j].y
+	})
+	return points
+}
+
+func floodFill(
+	screen tcell.Screen,
+	x, y, minX, minY, maxX, maxY, connectivity int,
+	match string,
+	tolerance int,
+	letter rune,
+	style tcell.Style,
+	send bool,
+) {
+	drawPoints(screen, fillPoints(x, y, minX, minY, maxX, maxY, connectivity, match, tolerance), letter, style)
+	if len(connections) > 0 && send {
+		foregroundColorName, backgroundColorName, attributes := formatStyle(style)
+		broadcastOnLayer(fmt.Sprintf(
+			"fill:%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v\n",
+			x,
+			y,
+			minX,
+			minY,
+			maxX,
+			maxY,
+			connectivity,
+			match,
+			tolerance,
+			foregroundColorName,
+			backgroundColorName,
+			attributes,
+			string(letter),
+		))
+	}
+}
+
+func (dialog *fillDialog) draw(screen tcell.Screen) {
+	dialog.x, dialog.y = centeredBox(screen, fillDialogWidth, fillDialogHeight)
+
+	defaultStyle := tcell.StyleDefault.Foreground(tcell.ColorWhite)
+	selectedStyle := defaultStyle.Reverse(true)
+	drawBox(screen, dialog.x, dialog.y, dialog.x+fillDialogWidth-1, dialog.y+fillDialogHeight-1, "Fill Options", defaultStyle)
+
+	drawText(screen, dialog.x+2, dialog.y+1, "Connectivity", defaultStyle)
+	for index, connectivity := range []int{4, 8} {
+		style := defaultStyle
+		if connectivity == fillConnectivity {
+			style = selectedStyle
+		}
+		drawText(screen, dialog.x+16+index*4, dialog.y+1, " "+strconv.Itoa(connectivity)+" ", style)
+	}
+	drawText(screen, dialog.x+2, dialog.y+2, "Match", defaultStyle)
+	matchOffset := dialog.x + 16
+	for _, match := range fillMatchModes {
+		style := defaultStyle
+		if match == fillMatch {
+			style = selectedStyle
+		}
+		drawText(screen, matchOffset, dialog.y+2, match, style)
+		matchOffset += len(match) + 1
+	}
+	drawText(screen, dialog.x+2, dialog.y+3, "Tolerance", defaultStyle)
+	drawText(screen, dialog.x+16, dialog.y+3, fmt.Sprintf("- %3d%% +", fillTolerance), defaultStyle)
+	drawText(screen, dialog.x+2, dialog.y+5, pickerOkLabel, defaultStyle)
+}
+
+func (dialog *fillDialog) handleEvent(event tcell.Event) bool {
+	switch event := event.(type) {
+	case *tcell.EventKey:
+		switch event.Key() {
+		case tcell.KeyEscape, tcell.KeyEnter:
+			return true
+		case tcell.KeyLeft:
+			fillTolerance--
+		case tcell.KeyRight:
+			fillTolerance++
+		}
+	case *tcell.EventMouse:
+		if event.Buttons() != tcell.Button1 {
+			return false
+		}
+		x, y := event.Position()
+		if y == dialog.y+1 && x >= dialog.x+16 && x < dialog.x+23 {
+			fillConnectivity = 4
+			if x >= dialog.x+20 {
+				fillConnectivity = 8
+			}
+		} else if y == dialog.y+2 {
+			matchOffset := dialog.x + 16
+			for _, match := range fillMatchModes {
+				if x >= matchOffset && x < matchOffset+len(match) {
+					fillMatch = match
+				}
+				matchOffset += len(match) + 1
+			}
+		} else if y == dialog.y+3 {
+			if x == dialog.x+16 {
+				fillTolerance -= 5
+			} else if x == dialog.x+23 {
+				fillTolerance += 5
+			}
+		} else if y == dialog.y+5 && x >= dialog.x+2 && x < dialog.x+2+len(pickerOkLabel) {
+			return true
+		}
+	}
+	if fillTolerance < 0 {
+		fillTolerance = 0
+	} else if fillTolerance > 100 {
+		fillTolerance = 100
+	}
+	return false
+}
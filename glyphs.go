@@ -0,0 +1,195 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+)
+
+const (
+	glyphColumns      = 24
+	glyphRows         = 11
+	glyphPickerWidth  = glyphColumns*2 + 4
+	glyphPickerHeight = glyphRows + 8
+)
+
+type glyphCategory struct {
+	name   string
+	glyphs []rune
+}
+
+var glyphCategories = []glyphCategory{
+	{"Shades", []rune("█▓▒░")},
+	{"Blocks", []rune("▀▄▌▐▖▗▘▝▚▞▙▛▜▟▁▂▃▅▆▇▉▊▋▍▎▏▔▕")},
+	{"Box", []rune("─│┌┐└┘├┤┬┴┼═║╔╗╚╝╠╣╦╩╬╭╮╯╰━┃┏┓┗┛┣┫┳┻╋╴╵╶╷")},
+	{"Braille", brailleGlyphs()},
+	{"Symbols", []rune("●○◆◇■□▲△▼▽◀▶★☆♥♦♣♠•◘◙☺☻♪♫✓✗")},
+	{"Wide", []rune("＠＃＊＋＝（）［］漢字口田🙂🔥🌲🏠🌊🍎")},
+}
+
+type glyphPicker struct {
+	category int
+	index    int
+	input    string
+	accepted bool
+	x, y     int
+}
+
+func brailleGlyphs() []rune {
+	glyphs := make([]rune, 0, 256)
+	for glyph := rune(0x2800); glyph <= 0x28FF; glyph++ {
+		glyphs = append(glyphs, glyph)
+	}
+	return glyphs
+}
+
+func newGlyphPicker(glyph rune) *glyphPicker {
+	picker := &glyphPicker{}
+	for categoryIndex, category := range glyphCategories {
+		for index, existingGlyph := range category.glyphs {
+			if existingGlyph == glyph {
+				picker.category = categoryIndex
+				picker.index = index
+				return picker
+			}
+		}
+	}
+	picker.input = string(glyph)
+	return picker
+}
+
+func (picker *glyphPicker) glyph() rune {
+	if glyph, ok := parseGlyph(picker.input); ok {
+		return glyph
+	}
+	return glyphCategories[picker.category].glyphs[picker.index]
+}
+
+func parseGlyph(input string) (rune, bool) {
+	if input == "" {
+		return 0, false
+	}
+	if strings.HasPrefix(strings.ToUpper(input), "U+") {
+		codepoint, err := strconv.ParseInt(input[2:], 16, 32)
+		if err == nil && codepoint > 0 {
+			return rune(codepoint), true
+		}
+		return 0, false
+	}
+	return []rune(input)[0], true
+}
+
+func (picker *glyphPicker) draw(screen tcell.Screen) {
+	picker.x, picker.y = centeredBox(screen, glyphPickerWidth, glyphPickerHeight)
+
+	defaultStyle := tcell.StyleDefault.Foreground(tcell.ColorWhite)
+	drawBox(screen, picker.x, picker.y, picker.x+glyphPickerWidth-1, picker.y+glyphPickerHeight-1, "Brush", defaultStyle)
+	tabOffset := picker.x + 2
+	for categoryIndex, category := range glyphCategories {
+		tabStyle := defaultStyle
+		if categoryIndex == picker.category {
+			tabStyle = tabStyle.Reverse(true)
+		}
+		drawText(screen, tabOffset, picker.y+1, category.name, tabStyle)
+		tabOffset += len(category.name) + 1
+	}
+
+	glyphStyle := adaptStyle(paintStyle(), screen.Colors())
+	for index, glyph := range glyphCategories[picker.category].glyphs {
+		if index >= glyphColumns*glyphRows {
+			break
+		}
+		style := glyphStyle
+		if index == picker.index && picker.input == "" {
+			style = style.Reverse(true)
+		}
+		screen.SetContent(picker.x+2+(index%glyphColumns)*2, picker.y+3+index/glyphColumns, glyph, nil, style)
+	}
+
+	inputText := picker.input
+	if inputText == "" {
+		inputText = "type a character or U+XXXX"
+	}
+	drawText(screen, picker.x+2, picker.y+glyphRows+4, "Custom: "+inputText, defaultStyle)
+	drawText(screen, picker.x+2, picker.y+glyphRows+5, "Brush: ", defaultStyle)
+	screen.SetContent(picker.x+9, picker.y+glyphRows+5, picker.glyph(), nil, glyphStyle)
+	drawText(screen, picker.x+12, picker.y+glyphRows+5, "width "+strconv.Itoa(runewidth.RuneWidth(picker.glyph())), defaultStyle)
+	drawText(screen, picker.x+2, picker.y+glyphRows+6, pickerOkLabel, defaultStyle)
+	drawText(screen, picker.x+4+len(pickerOkLabel), picker.y+glyphRows+6, pickerCancelLabel, defaultStyle)
+}
+
+func (picker *glyphPicker) moveSelection(offset int) {
+	glyphCount := len(glyphCategories[picker.category].glyphs)
+	picker.input = ""
+	picker.index = (picker.index + offset + glyphCount) % glyphCount
+}
+
+func (picker *glyphPicker) handleEvent(event tcell.Event) bool {
+	switch event := event.(type) {
+	case *tcell.EventKey:
+		switch event.Key() {
+		case tcell.KeyEscape:
+			return true
+		case tcell.KeyEnter:
+			picker.accepted = runewidth.RuneWidth(picker.glyph()) > 0
+			return true
+		case tcell.KeyTab:
+			picker.category = (picker.category + 1) % len(glyphCategories)
+			picker.index = 0
+			picker.input = ""
+		case tcell.KeyBacktab:
+			picker.category = (picker.category + len(glyphCategories) - 1) % len(glyphCategories)
+			picker.index = 0
+			picker.input = ""
+		case tcell.KeyLeft:
+			picker.moveSelection(-1)
+		case tcell.KeyRight:
+			picker.moveSelection(1)
+		case tcell.KeyUp:
+			picker.moveSelection(-glyphColumns)
+		case tcell.KeyDown:
+			picker.moveSelection(glyphColumns)
+		case tcell.KeyBackspace, tcell.KeyBackspace2:
+			if len(picker.input) > 0 {
+				letters := []rune(picker.input)
+				picker.input = string(letters[:len(letters)-1])
+			}
+		case tcell.KeyRune:
+			picker.input += string(event.Rune())
+		}
+	case *tcell.EventMouse:
+		if event.Buttons() != tcell.Button1 {
+			return false
+		}
+		x, y := event.Position()
+		if y == picker.y+1 {
+			tabOffset := picker.x + 2
+			for categoryIndex, category := range glyphCategories {
+				if x >= tabOffset && x < tabOffset+len(category.name) {
+					picker.category = categoryIndex
+					picker.index = 0
+					picker.input = ""
+				}
+				tabOffset += len(category.name) + 1
+			}
+		} else if y >= picker.y+3 && y < picker.y+3+glyphRows && x >= picker.x+2 && x < picker.x+2+glyphColumns*2 {
+			index := (y-picker.y-3)*glyphColumns + (x-picker.x-2)/2
+			if index < len(glyphCategories[picker.category].glyphs) {
+				picker.index = index
+				picker.input = ""
+			}
+		} else if y == picker.y+glyphRows+6 {
+			okOffset := picker.x + 2
+			cancelOffset := picker.x + 4 + len(pickerOkLabel)
+			if x >= okOffset && x < okOffset+len(pickerOkLabel) {
+				picker.accepted = runewidth.RuneWidth(picker.glyph()) > 0
+				return true
+			} else if x >= cancelOffset && x < cancelOffset+len(pickerCancelLabel) {
+				return true
+			}
+		}
+	}
+	return false
+}
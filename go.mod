@@ -2,12 +2,14 @@ module github.com/ErrorNoInternet/termcanvas
 
 go 1.18
 
-require github.com/gdamore/tcell/v2 v2.5.4
+require (
+	github.com/gdamore/tcell/v2 v2.5.4
+	github.com/lucasb-eyer/go-colorful v1.2.0
+	github.com/mattn/go-runewidth v0.0.14
+)
 
 require (
 	github.com/gdamore/encoding v1.0.0 // indirect
-	github.com/lucasb-eyer/go-colorful v1.2.0 // indirect
-	github.com/mattn/go-runewidth v0.0.14 // indirect
 	github.com/rivo/uniseg v0.4.3 // indirect
 	golang.org/x/sys v0.3.0 // indirect
 	golang.org/x/term v0.3.0 // indirect
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+const (
+	defaultGridSize   = 8
+	rulerWidth        = 5
+	rulerHeight       = 1
+	guideSnapDistance = 2
+)
+
+type rulerStep struct {
+	major, minor int
+}
+
+type guideOverlay struct {
+	grid     bool
+	gridSize int
+	rulers   bool
+	guidesX  []int
+	guidesY  []int
+}
+
+var (
+	guides     = &guideOverlay{gridSize: defaultGridSize}
+	rulerSteps = []rulerStep{
+		{1, 0},
+		{2, 1},
+		{5, 1},
+		{10, 5},
+		{20, 10},
+		{50, 10},
+		{100, 50},
+		{200, 100},
+		{500, 100},
+		{1000, 500},
+	}
+	rulerStyle = tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.ColorGray)
+	gridStyle  = tcell.StyleDefault.Foreground(tcell.ColorDimGray)
+	guideStyle = tcell.StyleDefault.Foreground(tcell.ColorAqua)
+)
+
+func toggleGuide(positions []int, position int) []int {
+	for index, existingPosition := range positions {
+		if existingPosition == position {
+			return append(positions[:index], positions[index+1:]...)
+		}
+	}
+	positions = append(positions, position)
+	sort.Ints(positions)
+	return positions
+}
+
+func (overlay *guideOverlay) toggleVertical(x int) {
+	overlay.guidesX = toggleGuide(overlay.guidesX, x)
+}
+
+func (overlay *guideOverlay) toggleHorizontal(y int) {
+	overlay.guidesY = toggleGuide(overlay.guidesY, y)
+}
+
+func (overlay *guideOverlay) clear() {
+	overlay.guidesX, overlay.guidesY = nil, nil
+}
+
+func snapPosition(position int, positions []int) int {
+	snapped, distance := position, guideSnapDistance+1
+	for _, guidePosition := range positions {
+		guideDistance := guidePosition - position
+		if guideDistance < 0 {
+			guideDistance = -guideDistance
+		}
+		if guideDistance < distance {
+			snapped, distance = guidePosition, guideDistance
+		}
+	}
+	return snapped
+}
+
+func (overlay *guideOverlay) snap(x, y int) (int, int) {
+	return snapPosition(x, overlay.guidesX), snapPosition(y, overlay.guidesY)
+}
+
+func crossesLine(previous, current, size int) bool {
+	return floorDivide(previous, size) != floorDivide(current, size)
+}
+
+func crossesGuide(previous, current int, positions []int) bool {
+	for _, position := range positions {
+		if previous < position && position <= current {
+			return true
+		}
+	}
+	return false
+}
+
+func (overlay *guideOverlay) draw(view *canvasView) {
+	if !overlay.grid && len(overlay.guidesX) == 0 && len(overlay.guidesY) == 0 {
+		return
+	}
+	width, height := view.Screen.Size()
+	marginX, marginY := view.margins()
+	colorCount := view.Screen.Colors()
+
+	columns := make(map[int][2]bool)
+	for x := marginX; x < width; x++ {
+		previous, current := view.canvasColumn(x-1), view.canvasColumn(x)
+		columns[x] = [2]bool{overlay.grid && crossesLine(previous, current, overlay.gridSize), crossesGuide(previous, current, overlay.guidesX)}
+	}
+	for y := toolbarHeight + marginY; y < height-statusBarHeight; y++ {
+		previous, current := view.canvasRow(y-1), view.canvasRow(y)
+		gridRow := overlay.grid && crossesLine(previous-toolbarHeight, current-toolbarHeight, overlay.gridSize)
+		guideRow := crossesGuide(previous, current, overlay.guidesY)
+		for x := marginX; x < width; x++ {
+			vertical := columns[x][0] || columns[x][1]
+			horizontal := gridRow || guideRow
+			if !vertical && !horizontal {
+				continue
+			}
+			character, _, style, _ := view.Screen.GetContent(x, y)
+			_, backgroundColor, _ := style.Decompose()
+			if character != ' ' || normalizeColor(backgroundColor) != tcell.ColorDefault {
+				continue
+			}
+			character = '│'
+			if vertical && horizontal {
+				character = '┼'
+			} else if horizontal {
+				character = '─'
+			}
+			style = gridStyle
+			if columns[x][1] || guideRow {
+				style = guideStyle
+			}
+			view.Screen.SetContent(x, y, character, nil, adaptStyle(style, colorCount))
+		}
+	}
+}
+
+func chooseRulerStep(scale, sample, spacing int) rulerStep {
+	for _, step := range rulerSteps {
+		if step.major*scale/sample >= spacing {
+			return step
+		}
+	}
+	return rulerSteps[len(rulerSteps)-1]
+}
+
+func (overlay *guideOverlay) drawRulers(view *canvasView, cursorX, cursorY int) {
+	if !overlay.rulers {
+		return
+	}
+	width, height := view.Screen.Size()
+	level := view.level()
+	colorCount := view.Screen.Colors()
+	style := adaptStyle(rulerStyle, colorCount)
+	markerStyle := adaptStyle(rulerStyle.Foreground(tcell.ColorAqua).Bold(true), colorCount)
+	cursorScreenX, cursorScreenY := view.screenPoint(cursorX, cursorY)
+
+	for x := 0; x < width; x++ {
+		view.Screen.SetContent(x, toolbarHeight, ' ', nil, style)
+	}
+	step := chooseRulerStep(level.scaleX, level.sampleX, 6)
+	for x := rulerWidth; x < width; x++ {
+		previous, current := view.canvasColumn(x-1), view.canvasColumn(x)
+		if crossesLine(previous, current, step.major) {
+			label := fmt.Sprint(floorDivide(current, step.major) * step.major)
+			drawText(view.Screen, x, toolbarHeight, label, style)
+			x += len(label)
+		} else if step.minor != 0 && crossesLine(previous, current, step.minor) {
+			view.Screen.SetContent(x, toolbarHeight, '·', nil, style)
+		}
+	}
+
+	step = chooseRulerStep(level.scaleY, level.sampleY, 4)
+	for y := toolbarHeight + rulerHeight; y < height-statusBarHeight; y++ {
+		for x := 0; x < rulerWidth; x++ {
+			view.Screen.SetContent(x, y, ' ', nil, style)
+		}
+		previous, current := view.canvasRow(y-1)-toolbarHeight, view.canvasRow(y)-toolbarHeight
+		if crossesLine(previous, current, step.major) {
+			label := fmt.Sprint(floorDivide(current, step.major) * step.major)
+			drawText(view.Screen, rulerWidth-1-len(label), y, label, style)
+		} else if step.minor != 0 && crossesLine(previous, current, step.minor) {
+			view.Screen.SetContent(rulerWidth-2, y, '·', nil, style)
+		}
+	}
+
+	for x := rulerWidth; x < width; x++ {
+		if crossesGuide(view.canvasColumn(x-1), view.canvasColumn(x), overlay.guidesX) {
+			view.Screen.SetContent(x, toolbarHeight, '▼', nil, markerStyle)
+		}
+	}
+	for y := toolbarHeight + rulerHeight; y < height-statusBarHeight; y++ {
+		if crossesGuide(view.canvasRow(y-1), view.canvasRow(y), overlay.guidesY) {
+			view.Screen.SetContent(rulerWidth-1, y, '▶', nil, markerStyle)
+		}
+	}
+	if cursorScreenX >= rulerWidth && cursorScreenX < width {
+		character, _, cellStyle, _ := view.Screen.GetContent(cursorScreenX, toolbarHeight)
+		view.Screen.SetContent(cursorScreenX, toolbarHeight, character, nil, cellStyle.Reverse(true))
+	}
+	if cursorScreenY >= toolbarHeight+rulerHeight && cursorScreenY < height-statusBarHeight {
+		for x := 0; x < rulerWidth; x++ {
+			character, _, cellStyle, _ := view.Screen.GetContent(x, cursorScreenY)
+			view.Screen.SetContent(x, cursorScreenY, character, nil, cellStyle.Reverse(true))
+		}
+	}
+}
+
+func (overlay *guideOverlay) rulerContains(x, y int) bool {
+	return overlay.rulers && y >= toolbarHeight && (y < toolbarHeight+rulerHeight || x < rulerWidth)
+}
+
+func (overlay *guideOverlay) handleRulerClick(view *canvasView, x, y int) {
+	if y < toolbarHeight+rulerHeight && x >= rulerWidth {
+		overlay.toggleVertical(view.canvasColumn(x))
+	} else if y >= toolbarHeight+rulerHeight && x < rulerWidth {
+		overlay.toggleHorizontal(view.canvasRow(y))
+	}
+}
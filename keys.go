@@ -0,0 +1,228 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+var (
+	keyBindings = map[string][]string{
+		"keyboard-mode":            {"tab"},
+		"command-line":             {":"},
+		"cursor-left":              {"left", "h"},
+		"cursor-right":             {"right", "l"},
+		"cursor-up":                {"up", "k"},
+		"cursor-down":              {"down", "j"},
+		"cursor-left-fast":         {"shift+left"},
+		"cursor-right-fast":        {"shift+right"},
+		"cursor-up-fast":           {"shift+up"},
+		"cursor-down-fast":         {"shift+down"},
+		"pan-left":                 {"alt+left"},
+		"pan-right":                {"alt+right"},
+		"pan-up":                   {"alt+up"},
+		"pan-down":                 {"alt+down"},
+		"paint":                    {"space"},
+		"erase":                    {"x"},
+		"copy":                     {"ctrl+c"},
+		"cut":                      {"ctrl+x"},
+		"paste":                    {"ctrl+v"},
+		"save-stamp":               {"ctrl+s"},
+		"clear-selection":          {"delete", "backspace"},
+		"delete-stamp":             {"delete"},
+		"flip-horizontal":          {"f", "|"},
+		"flip-vertical":            {"v", "_"},
+		"rotate":                   {"r"},
+		"pencil":                   {"1"},
+		"line":                     {"2"},
+		"region":                   {"3"},
+		"border":                   {"4"},
+		"ellipse":                  {"5"},
+		"disc":                     {"6"},
+		"fill":                     {"7"},
+		"select":                   {"8"},
+		"stamp":                    {"9"},
+		"banner":                   {"0"},
+		"text":                     {"t"},
+		"tool-options":             {"o"},
+		"next-color":               {"]"},
+		"previous-color":           {"["},
+		"next-secondary-color":     {"}"},
+		"previous-secondary-color": {"{"},
+		"swap-colors":              {"s"},
+		"color-picker":             {"c"},
+		"secondary-color-picker":   {"C"},
+		"brush-picker":             {"b"},
+		"bold":                     {"B"},
+		"italic":                   {"I"},
+		"underline":                {"U"},
+		"reverse":                  {"R"},
+		"blink":                    {"K"},
+		"mirror-axis":              {"M"},
+		"minimap":                  {"n"},
+		"grid":                     {"g"},
+		"rulers":                   {"G"},
+		"guide-vertical":           {"alt+v"},
+		"guide-horizontal":         {"alt+h"},
+		"clear-guides":             {"alt+g"},
+		"zoom-in":                  {"+", "="},
+		"zoom-out":                 {"-"},
+		"save":                     {"w"},
+		"load":                     {"e"},
+		"palette":                  {"p"},
+		"layers":                   {"y"},
+		"mirror":                   {"m"},
+		"clear":                    {"X"},
+		"exit":                     {"esc", "q"},
+	}
+	cursorBindings = []string{
+		"cursor-left", "cursor-right", "cursor-up", "cursor-down",
+		"cursor-left-fast", "cursor-right-fast", "cursor-up-fast", "cursor-down-fast",
+		"paint", "erase",
+	}
+	selectBindings = []string{"copy", "cut", "paste", "save-stamp", "clear-selection"}
+	stampBindings  = []string{"delete-stamp", "flip-horizontal", "flip-vertical", "rotate"}
+	globalBindings = []string{
+		"keyboard-mode", "command-line",
+		"pencil", "line", "region", "border", "ellipse", "disc", "fill", "select", "stamp", "banner", "text",
+		"tool-options",
+		"next-color", "previous-color", "next-secondary-color", "previous-secondary-color", "swap-colors",
+		"color-picker", "secondary-color-picker", "brush-picker",
+		"bold", "italic", "underline", "reverse", "blink",
+		"mirror-axis", "zoom-in", "zoom-out", "pan-left", "pan-right", "pan-up", "pan-down", "minimap",
+		"grid", "rulers", "guide-vertical", "guide-horizontal", "clear-guides",
+		"save", "load", "palette", "layers", "mirror", "clear", "exit",
+	}
+	attributeBindings = []string{"bold", "italic", "underline", "reverse", "blink"}
+	cursorMoves       = map[string]point{
+		"cursor-left":       {-1, 0},
+		"cursor-right":      {1, 0},
+		"cursor-up":         {0, -1},
+		"cursor-down":       {0, 1},
+		"cursor-left-fast":  {-8, 0},
+		"cursor-right-fast": {8, 0},
+		"cursor-up-fast":    {0, -4},
+		"cursor-down-fast":  {0, 4},
+	}
+	panMoves = map[string]point{
+		"pan-left":  {-8, 0},
+		"pan-right": {8, 0},
+		"pan-up":    {0, -4},
+		"pan-down":  {0, 4},
+	}
+	keyAliases = map[string]string{
+		"escape":     "esc",
+		"return":     "enter",
+		"del":        "delete",
+		"pageup":     "pgup",
+		"pagedown":   "pgdn",
+		"spacebar":   "space",
+		"control":    "ctrl",
+		"backspace2": "backspace",
+	}
+)
+
+func applyKeyBindings(bindings map[string][]string) error {
+	for action, keys := range bindings {
+		if _, ok := keyBindings[action]; !ok {
+			return fmt.Errorf("unknown action %q", action)
+		}
+		normalizedKeys := make([]string, len(keys))
+		for index, key := range keys {
+			normalizedKeys[index] = normalizeKey(key)
+		}
+		keyBindings[action] = normalizedKeys
+	}
+	return nil
+}
+
+func normalizeKey(key string) string {
+	key = strings.TrimSpace(key)
+	if len([]rune(key)) == 1 {
+		return key
+	}
+	parts := strings.Split(key, "+")
+	for index, part := range parts[:len(parts)-1] {
+		parts[index] = normalizeKeyPart(part)
+	}
+	last := len(parts) - 1
+	if len([]rune(parts[last])) != 1 || parts[0] == "ctrl" {
+		parts[last] = normalizeKeyPart(parts[last])
+	}
+	return strings.Join(parts, "+")
+}
+
+func normalizeKeyPart(part string) string {
+	part = strings.ToLower(part)
+	if alias, ok := keyAliases[part]; ok {
+		return alias
+	}
+	return part
+}
+
+func keyName(event *tcell.EventKey) string {
+	var name string
+	switch event.Key() {
+	case tcell.KeyRune:
+		name = string(event.Rune())
+		if event.Rune() == ' ' {
+			name = "space"
+		}
+	case tcell.KeyBackspace2:
+		name = "backspace"
+	default:
+		name = strings.ToLower(tcell.KeyNames[event.Key()])
+		if strings.HasPrefix(name, "ctrl-") {
+			return "ctrl+" + name[len("ctrl-"):]
+		}
+		if event.Modifiers()&tcell.ModShift != 0 {
+			name = "shift+" + name
+		}
+	}
+	if event.Modifiers()&tcell.ModAlt != 0 {
+		name = "alt+" + name
+	}
+	if event.Modifiers()&tcell.ModCtrl != 0 && event.Key() != tcell.KeyRune {
+		name = "ctrl+" + name
+	}
+	return name
+}
+
+func boundAction(key string, actions []string) string {
+	for _, action := range actions {
+		for _, boundKey := range keyBindings[action] {
+			if boundKey == key {
+				return action
+			}
+		}
+	}
+	return ""
+}
+
+func clampCursor(x, y int) (int, int) {
+	if x < 0 {
+		x = 0
+	}
+	if y < toolbarHeight {
+		y = toolbarHeight
+	}
+	if width, height := canvas.size(); width != 0 {
+		if x > width-1 {
+			x = width - 1
+		}
+		if y > height+toolbarHeight-1 {
+			y = height + toolbarHeight - 1
+		}
+	}
+	return x, y
+}
+
+func cycleColor(color string, offset int) string {
+	for index, paletteColor := range colors {
+		if paletteColor == color {
+			return colors[(index+offset+len(colors))%len(colors)]
+		}
+	}
+	return colors[0]
+}
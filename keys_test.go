@@ -0,0 +1,33 @@
+package main
+
+import "testing"
+
+func TestNormalizeKey(t *testing.T) {
+	tests := map[string]string{
+		"Ctrl+C":     "ctrl+c",
+		"Control+X":  "ctrl+x",
+		"Alt+H":      "alt+H",
+		"Shift+Left": "shift+left",
+		"Escape":     "esc",
+		"H":          "H",
+	}
+	for key, want := range tests {
+		if got := normalizeKey(key); got != want {
+			t.Errorf("normalizeKey(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestDefaultBindingsUnambiguous(t *testing.T) {
+	actions := make(map[string]string)
+	for _, group := range [][]string{cursorBindings, stampBindings, globalBindings} {
+		for _, action := range group {
+			for _, key := range keyBindings[action] {
+				if existingAction, ok := actions[key]; ok {
+					t.Errorf("%v is bound to both %v and %v", key, existingAction, action)
+				}
+				actions[key] = action
+			}
+		}
+	}
+}
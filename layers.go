@@ -0,0 +1,137 @@
+package main
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+)
+
+const (
+	layerDialogWidth = 48
+	layerNewLabel    = "[ New ]"
+	layerRenameLabel = "[ Rename ]"
+	layerDeleteLabel = "[ Delete ]"
+)
+
+type layerDialog struct {
+	x, y   int
+	action string
+}
+
+var layerButtons = []string{layerNewLabel, layerRenameLabel, layerDeleteLabel, pickerOkLabel}
+
+func activeLayerIndex(layers []layer) int {
+	activeName := canvas.activeLayer().name
+	for index, existingLayer := range layers {
+		if existingLayer.name == activeName {
+			return index
+		}
+	}
+	return 0
+}
+
+func updateLayer(name string, index int, visible, locked bool) {
+	canvas.updateLayer(name, index, visible, locked)
+	broadcastLayer(name)
+}
+
+func (dialog *layerDialog) draw(screen tcell.Screen) {
+	layers := canvas.layerList()
+	activeIndex := activeLayerIndex(layers)
+	dialogHeight := len(layers) + 4
+	dialog.x, dialog.y = centeredBox(screen, layerDialogWidth, dialogHeight)
+
+	defaultStyle := tcell.StyleDefault.Foreground(tcell.ColorWhite)
+	drawBox(screen, dialog.x, dialog.y, dialog.x+layerDialogWidth-1, dialog.y+dialogHeight-1, "Layers", defaultStyle)
+	for row := range layers {
+		index := len(layers) - 1 - row
+		existingLayer := layers[index]
+		y := dialog.y + 1 + row
+		nameStyle := defaultStyle
+		if index == activeIndex {
+			nameStyle = nameStyle.Reverse(true)
+		}
+		visibleLabel, lockedLabel := "[ ]", "[ ]"
+		if existingLayer.visible {
+			visibleLabel = "[V]"
+		}
+		if existingLayer.locked {
+			lockedLabel = "[L]"
+		}
+		drawText(screen, dialog.x+2, y, visibleLabel, defaultStyle)
+		drawText(screen, dialog.x+6, y, lockedLabel, defaultStyle)
+		drawText(screen, dialog.x+10, y, runewidth.Truncate(existingLayer.name, layerDialogWidth-17, "…"), nameStyle)
+		if index < len(layers)-1 {
+			screen.SetContent(dialog.x+layerDialogWidth-5, y, '▲', nil, defaultStyle)
+		}
+		if index > 0 {
+			screen.SetContent(dialog.x+layerDialogWidth-3, y, '▼', nil, defaultStyle)
+		}
+	}
+	buttonOffset := dialog.x + 2
+	for _, button := range layerButtons {
+		drawText(screen, buttonOffset, dialog.y+dialogHeight-2, button, defaultStyle)
+		buttonOffset += len(button) + 1
+	}
+}
+
+func (dialog *layerDialog) handleEvent(event tcell.Event) bool {
+	layers := canvas.layerList()
+	activeIndex := activeLayerIndex(layers)
+	activeLayer := layers[activeIndex]
+	switch event := event.(type) {
+	case *tcell.EventKey:
+		switch event.Key() {
+		case tcell.KeyEscape, tcell.KeyEnter:
+			return true
+		case tcell.KeyUp:
+			canvas.setActiveLayer(activeIndex + 1)
+		case tcell.KeyDown:
+			canvas.setActiveLayer(activeIndex - 1)
+		case tcell.KeyRune:
+			switch event.Rune() {
+			case 'v':
+				updateLayer(activeLayer.name, activeIndex, !activeLayer.visible, activeLayer.locked)
+			case 'l':
+				updateLayer(activeLayer.name, activeIndex, activeLayer.visible, !activeLayer.locked)
+			}
+		}
+	case *tcell.EventMouse:
+		if event.Buttons() != tcell.Button1 {
+			return false
+		}
+		x, y := event.Position()
+		row := y - dialog.y - 1
+		if row >= 0 && row < len(layers) {
+			index := len(layers) - 1 - row
+			existingLayer := layers[index]
+			if x >= dialog.x+2 && x < dialog.x+5 {
+				updateLayer(existingLayer.name, index, !existingLayer.visible, existingLayer.locked)
+			} else if x >= dialog.x+6 && x < dialog.x+9 {
+				updateLayer(existingLayer.name, index, existingLayer.visible, !existingLayer.locked)
+			} else if x == dialog.x+layerDialogWidth-5 && index < len(layers)-1 {
+				updateLayer(existingLayer.name, index+1, existingLayer.visible, existingLayer.locked)
+			} else if x == dialog.x+layerDialogWidth-3 && index > 0 {
+				updateLayer(existingLayer.name, index-1, existingLayer.visible, existingLayer.locked)
+			} else if x > dialog.x && x < dialog.x+layerDialogWidth-1 {
+				canvas.setActiveLayer(index)
+			}
+		} else if y == dialog.y+len(layers)+2 {
+			buttonOffset := dialog.x + 2
+			for _, button := range layerButtons {
+				if x >= buttonOffset && x < buttonOffset+len(button) {
+					switch button {
+					case layerNewLabel:
+						dialog.action = "new"
+					case layerRenameLabel:
+						dialog.action = "rename"
+					case layerDeleteLabel:
+						dialog.action = "delete"
+					}
+					return true
+				}
+				buttonOffset += len(button) + 1
+			}
+		}
+	}
+	return false
+}
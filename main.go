@@ -6,15 +6,19 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"syscall"
 
 	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
 )
 
 var (
-	block  rune     = '█'
-	colors []string = []string{
+	block         rune     = '█'
+	defaultColors []string = []string{
 		"black",
 		"maroon",
 		"green",
@@ -32,43 +36,75 @@ var (
 		"aqua",
 		"white",
 	}
-	tools = map[string]int{
-		"Pencil": 0,
-		"Region": 8,
-		"Border": 16,
-		"Text":   24,
+	colors []string = defaultColors
+	tools           = []string{
+		"Pencil",
+		"Line",
+		"Region",
+		"Border",
+		"Ellipse",
+		"Disc",
+		"Fill",
+		"Select",
+		"Stamp",
+		"Banner",
+		"Text",
 	}
-	actions = map[string]int{
-		"Save":  0,
-		"Load":  6,
-		"Clear": 12,
-		"Exit":  19,
+	actions    = []string{"Save", "Load", "Palette", "Layers", "Mirror", "Clear", "Exit"}
+	attributes = []tcell.AttrMask{
+		tcell.AttrBold,
+		tcell.AttrItalic,
+		tcell.AttrUnderline,
+		tcell.AttrReverse,
+		tcell.AttrBlink,
 	}
-	selectedColor string = "white"
-	selectedTool  string = "Pencil"
+	attributeLetters          = "biurk"
+	mirroredTools             = []string{"Pencil", "Line", "Region", "Fill"}
+	snappingTools             = []string{"Line", "Region", "Border", "Ellipse", "Disc", "Select"}
+	brush              rune   = block
+	primaryColor       string = "white"
+	secondaryColor     string = "reset"
+	selectedAttributes tcell.AttrMask
+	circleMode         bool
+	selectedTool       string = "Pencil"
 
 	hostServer     bool
 	connectAddress string
 	port           int
 	canvasFile     string
+	paletteFile    string
+	brushName      string
 	connections    []net.Conn
 )
 
 func setContent(screen tcell.Screen, x, y int, letter rune, style tcell.Style, send bool) {
-	screen.SetContent(x, y, letter, nil, style)
+	if letter != 0 {
+		screen.SetContent(x, y, letter, nil, adaptStyle(style, screen.Colors()))
+	}
+	if y >= toolbarHeight {
+		canvas.set(x, y, letter, style)
+	}
 
-	if len(connections) > 0 && y >= 4 && send {
-		for _, connection := range connections {
-			foregroundColorName, backgroundColorName := getColor(style)
-			if foregroundColorName == "" && backgroundColorName == "" {
-				foregroundColorName = "reset"
-				backgroundColorName = "reset"
-			}
-			go fmt.Fprintf(connection, fmt.Sprintf("set:%v,%v,%v,%v,%v\n", x, y, foregroundColorName, backgroundColorName, string(letter)))
-		}
+	if len(connections) > 0 && y >= toolbarHeight && send {
+		foregroundColorName, backgroundColorName, attributes := formatStyle(style)
+		broadcastOnLayer(fmt.Sprintf(
+			"set:%v,%v,%v,%v,%v,%v\n",
+			x,
+			y,
+			foregroundColorName,
+			backgroundColorName,
+			attributes,
+			string(letter),
+		))
 	}
 }
 
+func paintStyle() tcell.Style {
+	return tcell.StyleDefault.
+		Foreground(tcell.GetColor(primaryColor)).
+		Background(tcell.GetColor(secondaryColor))
+}
+
 func drawRegion(
 	screen tcell.Screen,
 	x1, y1, x2, y2 int,
@@ -86,52 +122,48 @@ func drawRegion(
 	}
 
 	if drawBorders {
-		for col := x1; col <= x2; col++ {
-			setContent(screen, col, y1, tcell.RuneHLine, borderStyle, false)
-			setContent(screen, col, y2, tcell.RuneHLine, borderStyle, false)
-		}
+		drawBorder(screen, x1, y1, x2, y2, borderStyle, "single", false)
+	}
+	letterWidth := runewidth.RuneWidth(letter)
+	if letterWidth < 1 {
+		letterWidth = 1
+	}
+	for _, activeMirror := range drawingSymmetry.mirrors() {
 		for row := y1 + 1; row < y2; row++ {
-			setContent(screen, x1, row, tcell.RuneVLine, borderStyle, false)
-			setContent(screen, x2, row, tcell.RuneVLine, borderStyle, false)
-		}
-		if y1 != y2 && x1 != x2 {
-			setContent(screen, x1, y1, tcell.RuneULCorner, borderStyle, false)
-			setContent(screen, x2, y1, tcell.RuneURCorner, borderStyle, false)
-			setContent(screen, x1, y2, tcell.RuneLLCorner, borderStyle, false)
-			setContent(screen, x2, y2, tcell.RuneLRCorner, borderStyle, false)
-		}
-	}
-	for row := y1 + 1; row < y2; row++ {
-		for col := x1 + 1; col < x2; col++ {
-			setContent(screen, col, row, letter, style, false)
-		}
-	}
-	if len(connections) > 0 && y1 >= 4 && send {
-		for _, connection := range connections {
-			foregroundColorName, backgroundColorName := getColor(style)
-			if foregroundColorName == "" && backgroundColorName == "" {
-				foregroundColorName = "reset"
-				backgroundColorName = "reset"
-			}
-			borderForegroundColorName, borderBackgroundColorName := getColor(borderStyle)
-			if borderForegroundColorName == "" && borderBackgroundColorName == "" {
-				borderForegroundColorName = "reset"
-				borderBackgroundColorName = "reset"
-			}
-			go fmt.Fprintf(connection, fmt.Sprintf(
-				"region:%v,%v,%v,%v,%v,%v,%v,%v,%v,%v\n",
-				x1,
-				y1,
-				x2,
-				y2,
-				foregroundColorName,
-				backgroundColorName,
-				borderForegroundColorName,
-				borderBackgroundColorName,
-				string(letter),
-				drawBorders,
-			))
+			for col := x1 + 1; col < x2; col += letterWidth {
+				regionLetter := letter
+				if col+letterWidth > x2 {
+					regionLetter = ' '
+				}
+				regionPoint := drawingSymmetry.mirrorPoint(point{col, row}, activeMirror, regionLetter)
+				setContent(screen, regionPoint.x, regionPoint.y, mirrorLetter(regionLetter, activeMirror), style, false)
+			}
+		}
+	}
+	if len(connections) > 0 && y1 >= toolbarHeight && send {
+		foregroundColorName, backgroundColorName := getColor(style)
+		if foregroundColorName == "" && backgroundColorName == "" {
+			foregroundColorName = "reset"
+			backgroundColorName = "reset"
+		}
+		borderForegroundColorName, borderBackgroundColorName := getColor(borderStyle)
+		if borderForegroundColorName == "" && borderBackgroundColorName == "" {
+			borderForegroundColorName = "reset"
+			borderBackgroundColorName = "reset"
 		}
+		broadcastOnLayer(fmt.Sprintf(
+			"region:%v,%v,%v,%v,%v,%v,%v,%v,%v,%v\n",
+			x1,
+			y1,
+			x2,
+			y2,
+			foregroundColorName,
+			backgroundColorName,
+			borderForegroundColorName,
+			borderBackgroundColorName,
+			string(letter),
+			drawBorders,
+		))
 	}
 }
 
@@ -151,16 +183,8 @@ func clearRegion(screen tcell.Screen, x1, y1, x2, y2 int, send bool) {
 			setContent(screen, col, row, ' ', defaultStyle, false)
 		}
 	}
-	if len(connections) > 0 && y1 >= 4 && send {
-		for _, connection := range connections {
-			go fmt.Fprintf(connection, fmt.Sprintf(
-				"clearRegion:%v,%v,%v,%v\n",
-				x1,
-				y1,
-				x2,
-				y2,
-			))
-		}
+	if len(connections) > 0 && y1 >= toolbarHeight && send {
+		broadcastOnLayer(fmt.Sprintf("clearRegion:%v,%v,%v,%v\n", x1, y1, x2, y2))
 	}
 }
 
@@ -169,8 +193,32 @@ func main() {
 	flag.StringVar(&connectAddress, "connect", "", "Connect to a termcanvas server")
 	flag.IntVar(&port, "port", 55055, "The port to host on or connect to")
 	flag.StringVar(&canvasFile, "canvas", "", "The canvas file to load")
+	flag.StringVar(&paletteFile, "palette", "", "The palette file to load (GIMP, JASC or hex list)")
+	flag.StringVar(&brushName, "brush", string(block), "The character to draw with (or a code point like U+2588)")
+	flag.StringVar(&primaryColor, "color", primaryColor, "The primary color (a color name or #RRGGBB)")
+	flag.StringVar(&secondaryColor, "secondary-color", secondaryColor, "The secondary color (a color name or #RRGGBB)")
+	flag.StringVar(&selectedTool, "tool", selectedTool, "The tool to start with")
+	flag.StringVar(&nickname, "nickname", "", "The name other players see you as")
+	flag.DurationVar(&autosaveInterval, "autosave", autosaveInterval, "How often to autosave the drawing for crash recovery (0 to disable)")
+	flag.StringVar(&configFile, "config", "", "The config file to use (defaults to ~/.config/termcanvas/config.json)")
 	flag.Parse()
 
+	loadedConfig, err := loadConfig()
+	if err == nil {
+		err = applyConfig(loadedConfig)
+	}
+	if err != nil {
+		fmt.Printf("Unable to load config: %v\n", err.Error())
+		os.Exit(1)
+	}
+	if flag.Arg(0) == "config" {
+		if err := printConfig(); err != nil {
+			fmt.Printf("Unable to print config: %v\n", err.Error())
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
 	screen, err := tcell.NewScreen()
 	if err != nil {
 		fmt.Printf("Unable to create screen: %v\n", err.Error())
@@ -187,9 +235,24 @@ func main() {
 	screen.EnableMouse()
 	screen.EnablePaste()
 	screen.Clear()
+	view := newCanvasView(screen)
+	status := newStatusBar(screen)
 	var pressed, erase bool
 	var startX, startY, lastX, lastY int
-	var textX, textY int = 0, 4
+	var editingText *textBox
+	var placingText bool
+	var dialog modal
+	var selectedArea *selection
+	var moving, panning, placingGuide bool
+	var movingCells map[point]cell
+	var cursorX, cursorY int = 0, toolbarHeight
+	var hoverX, hoverY int = -1, -1
+	var keyboardMode bool
+	var keyboardPen tcell.ButtonMask
+	var stamps *stampLibrary
+	var banner map[point]cell
+	var pickerTarget *string
+	var colorsScroll int
 
 	if hostServer && connectAddress != "" {
 		screen.Fini()
@@ -212,7 +275,7 @@ func main() {
 		go handleConnections(listener, screen)
 	}
 	if connectAddress != "" {
-		connection, err := net.Dial("tcp", connectAddress+":"+strconv.Itoa(port))
+		connection, err := net.Dial("tcp", serverAddress(connectAddress))
 		if err != nil {
 			screen.Fini()
 			fmt.Printf("Unable to connect to server: %v\n", err.Error())
@@ -226,247 +289,776 @@ func main() {
 			screen.Fini()
 			fmt.Printf("Unable to load %v: %v\n", canvasFile, err.Error())
 			os.Exit(1)
-		} else {
-			drawData(string(fileData), screen)
 		}
+		if err := drawData(string(fileData), view); err != nil {
+			screen.Fini()
+			fmt.Printf("Unable to load %v: %v\n", canvasFile, err.Error())
+			os.Exit(1)
+		}
+		lastFilePath = canvasFile
+		savedRevision = canvas.changes()
 	}
+	if paletteFile != "" {
+		palette, err := loadPalette(paletteFile)
+		if err != nil {
+			screen.Fini()
+			fmt.Printf("Unable to load %v: %v\n", paletteFile, err.Error())
+			os.Exit(1)
+		}
+		colors = palette
+	}
+	if recoveredData, ok := loadRecovery(); ok {
+		dialog = &confirmDialog{
+			title:   "Recovery",
+			message: "termcanvas didn't exit cleanly last time, restore the autosaved drawing?",
+			onClose: func(accepted bool) {
+				if !accepted {
+					removeStaleRecoveries()
+				} else if err := drawData(recoveredData, view); err != nil {
+					status.showError("Unable to restore drawing: " + err.Error())
+				} else {
+					if autosaveInterval != 0 {
+						writeRecovery()
+					}
+					removeStaleRecoveries()
+					status.show("Restored the autosaved drawing")
+					width, height := screen.Size()
+					screen.PostEvent(tcell.NewEventResize(width, height))
+				}
+				go autosave(autosaveInterval)
+			},
+		}
+	} else {
+		go autosave(autosaveInterval)
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP, syscall.SIGTERM)
+	go func() {
+		<-signals
+		stopAutosave()
+		screen.Fini()
+		os.Exit(1)
+	}()
 
-	colorsLength := len(colors)
-	toolsLength := 0
-	for tool := range tools {
-		toolsLength += len(tool) + 2
-	}
-	actionsLength := 0
-	for action := range actions {
-		actionsLength += len(action) + 2
-	}
-	colorsOffset := 7
-	toolsOffset := colorsOffset + colorsLength + 2
-	actionsOffset := toolsOffset + toolsLength + 2
-	remainingOffset := actionsOffset + actionsLength + 2
+	selectTool := func(tool string) {
+		if tool == "Fill" && selectedTool == "Fill" {
+			dialog = &fillDialog{}
+		} else if tool == "Border" && selectedTool == "Border" {
+			dialog = &borderDialog{}
+		} else if (tool == "Ellipse" || tool == "Disc") && selectedTool == tool {
+			circleMode = !circleMode
+		} else if tool == "Banner" {
+			dialog = newBannerDialog()
+		}
+		selectedTool = tool
+		selectedArea = nil
+		if editingText != nil {
+			commitText(view, editingText, true)
+			editingText = nil
+		}
+		if tool == "Stamp" {
+			stamps = loadStampLibrary()
+		}
+	}
+	if selectedTool == "Stamp" {
+		stamps = loadStampLibrary()
+	}
+	runAction := func(action string) {
+		if action == "Exit" {
+			exit(screen)
+		} else if action == "Layers" {
+			dialog = &layerDialog{}
+		} else if action == "Mirror" {
+			for index, mode := range symmetryModes {
+				if mode == currentSymmetry.mode {
+					currentSymmetry.mode = symmetryModes[(index+1)%len(symmetryModes)]
+					break
+				}
+			}
+			if currentSymmetry.axisX == 0 && currentSymmetry.axisY == 0 {
+				x1, y1, x2, y2 := view.bounds()
+				currentSymmetry = centeredSymmetry(currentSymmetry.mode, x1, y1, x2, y2)
+			}
+		} else if action == "Clear" {
+			screen.Clear()
+			canvas.clear()
+			broadcastOnLayer("clear\n")
+		} else if action == "Save" {
+			dialog = newFileBrowser("Save", lastFilePath, saveFormats, true, func(filePath string, format fileFormat) {
+				if err := saveCanvas(filePath, format); err != nil {
+					status.showError("Unable to write to file: " + err.Error())
+					return
+				}
+				if format.extension == ".csv" {
+					lastFilePath = filePath
+					savedRevision = canvas.changes()
+				}
+				status.show("Saved " + filepath.Base(filePath))
+			})
+		} else if action == "Load" {
+			dialog = newFileBrowser("Load", lastFilePath, loadFormats, false, func(filePath string, format fileFormat) {
+				if err := loadCanvas(view, filePath, format); err != nil {
+					status.showError("Unable to load " + filepath.Base(filePath) + ": " + err.Error())
+					return
+				}
+				if format.extension == ".csv" {
+					lastFilePath = filePath
+					savedRevision = canvas.changes()
+				}
+				status.show("Loaded " + filepath.Base(filePath))
+				screen.PostEvent(tcell.NewEventResize(screen.Size()))
+			})
+		} else if action == "Palette" {
+			browser := newFileBrowser("Palette", paletteFile, nil, false, func(filePath string, _ fileFormat) {
+				if filePath == "" {
+					colors = defaultColors
+					colorsScroll = 0
+					status.show("Switched to the default palette")
+					return
+				}
+				palette, err := loadPalette(filePath)
+				if err != nil {
+					status.showError("Unable to load " + filepath.Base(filePath) + ": " + err.Error())
+					return
+				}
+				paletteFile = filePath
+				colors = palette
+				colorsScroll = 0
+				status.show("Loaded " + filepath.Base(filePath))
+			})
+			browser.input = ""
+			browser.emptyHint = "Leave the name empty for the default palette"
+			dialog = browser
+		}
+	}
 
-	for {
-		width, height := screen.Size()
+	bar := &toolbar{
+		widgets: []toolbarWidget{
+			&colorBoxWidget{pick: func(target *string) {
+				pickerTarget = target
+				dialog = newColorPicker(*target)
+			}},
+			&brushBoxWidget{pick: func() {
+				dialog = newGlyphPicker(brush)
+			}},
+			&paletteWidget{colors: func() []string { return colors }, scroll: &colorsScroll},
+			&paletteWidget{colors: func() []string { return recentColors }, length: maxRecentColors, importance: 2},
+			toolButtons(selectTool),
+			attributeButtons(),
+			actionButtons(runAction),
+			&connectionsWidget{},
+		},
+		menu: &toolbarMenu{open: func(menu modal) {
+			dialog = menu
+		}},
+	}
 
-		drawRegion(screen, 0, 0, width, 3, defaultStyle, defaultStyle, ' ', false, false)
-		drawRegion(screen, 0, 0, 5, 3, tcell.StyleDefault.Foreground(tcell.GetColor(selectedColor)), defaultStyle, block, true, false)
-		drawRegion(screen, colorsOffset-1, 0, colorsLength+colorsOffset, 3, defaultStyle, defaultStyle, ' ', true, false)
-		for index, color := range colors {
-			drawRegion(screen,
-				index+(colorsOffset-1),
-				0,
-				index+(colorsOffset+1),
-				3,
-				tcell.StyleDefault.Foreground(tcell.GetColor(color)),
-				defaultStyle,
-				block,
-				false,
-				false,
-			)
-		}
-		drawRegion(screen, toolsOffset-1, 0, toolsLength+toolsOffset-2, 3, defaultStyle, defaultStyle, ' ', true, false)
-		for tool, offset := range tools {
-			for letterOffset, letter := range tool {
-				drawRegion(
-					screen,
-					toolsOffset+letterOffset+offset-1,
-					0,
-					toolsOffset+letterOffset+offset+1,
-					2,
-					tcell.StyleDefault.Foreground(tcell.ColorWhite),
-					defaultStyle,
-					letter,
-					false,
-					false,
-				)
-			}
-		}
-		selectedToolOffset := 0
-		for tool, offset := range tools {
-			if selectedTool == tool {
-				selectedToolOffset = offset
+	runBinding := func(action string) {
+		switch action {
+		case "cursor-left", "cursor-right", "cursor-up", "cursor-down",
+			"cursor-left-fast", "cursor-right-fast", "cursor-up-fast", "cursor-down-fast":
+			cursorX, cursorY = clampCursor(cursorX+cursorMoves[action].x, cursorY+cursorMoves[action].y)
+			view.follow(cursorX, cursorY)
+			if keyboardPen != tcell.ButtonNone {
+				screen.PostEvent(newCursorEvent(cursorX, cursorY, keyboardPen))
+			}
+		case "paint", "erase":
+			if keyboardPen != tcell.ButtonNone {
+				keyboardPen = tcell.ButtonNone
+			} else if action == "paint" {
+				keyboardPen = tcell.Button1
+			} else {
+				keyboardPen = tcell.Button2
+			}
+			screen.PostEvent(newCursorEvent(cursorX, cursorY, keyboardPen))
+		case "command-line":
+			dialog = newCommandLine()
+		case "zoom-in", "zoom-out":
+			offset := 1
+			if action == "zoom-out" {
+				offset = -1
+			}
+			view.setZoom(view.zoom+offset, cursorX, cursorY)
+		case "pan-left", "pan-right", "pan-up", "pan-down":
+			view.pan(panMoves[action].x, panMoves[action].y)
+		case "minimap":
+			overview.visible = !overview.visible
+		case "grid":
+			guides.grid = !guides.grid
+		case "rulers":
+			guides.rulers = !guides.rulers
+			view.clampOffset()
+		case "guide-vertical":
+			guides.toggleVertical(cursorX)
+		case "guide-horizontal":
+			guides.toggleHorizontal(cursorY)
+		case "clear-guides":
+			guides.clear()
+		case "keyboard-mode":
+			keyboardMode = !keyboardMode
+			if !keyboardMode && keyboardPen != tcell.ButtonNone {
+				keyboardPen = tcell.ButtonNone
+				screen.PostEvent(newCursorEvent(cursorX, cursorY, keyboardPen))
+			}
+		case "copy", "cut":
+			if selectedArea != nil {
+				setClipboard(copyCells(selectedArea))
+				if action == "cut" {
+					clearRegion(view, selectedArea.x1, selectedArea.y1, selectedArea.x2, selectedArea.y2, true)
+				}
+			}
+		case "paste":
+			cells := loadClipboard()
+			if len(cells) > 0 {
+				pasteCells(view, cursorX, cursorY, cells, true)
+				cellsWidth, cellsHeight := cellsSize(cells)
+				selectedArea = newSelection(cursorX, cursorY, cursorX+cellsWidth-1, cursorY+cellsHeight-1)
+			}
+		case "save-stamp":
+			if selectedArea != nil {
+				cells := copyCells(selectedArea)
+				dialog = &textPrompt{
+					title: "Stamp Name",
+					onAccept: func(name string) {
+						if err := saveStamp(name, cells); err != nil {
+							status.showError("Unable to save stamp: " + err.Error())
+							return
+						}
+						status.show("Saved stamp " + strings.TrimSuffix(strings.TrimSpace(name), ".csv"))
+					},
+				}
+			}
+		case "clear-selection":
+			if selectedArea != nil {
+				clearRegion(view, selectedArea.x1, selectedArea.y1, selectedArea.x2, selectedArea.y2, true)
+			}
+		case "delete-stamp":
+			if len(stamps.names) > 0 {
+				name := stamps.names[stamps.selected]
+				dialog = &confirmDialog{
+					title:   "Delete Stamp",
+					message: "Delete the stamp " + name + "?",
+					onClose: func(accepted bool) {
+						if !accepted {
+							return
+						}
+						if err := stamps.deleteSelected(); err != nil {
+							status.showError("Unable to delete stamp: " + err.Error())
+						}
+					},
+				}
+			}
+		case "flip-horizontal", "flip-vertical", "rotate":
+			if stamps.cells != nil {
+				switch action {
+				case "flip-horizontal":
+					stamps.cells = flipCellsHorizontally(stamps.cells)
+				case "flip-vertical":
+					stamps.cells = flipCellsVertically(stamps.cells)
+				case "rotate":
+					stamps.cells = rotateCells(stamps.cells)
+				}
+			}
+		case "tool-options":
+			selectTool(selectedTool)
+		case "next-color", "previous-color":
+			offset := 1
+			if action == "previous-color" {
+				offset = -1
+			}
+			primaryColor = cycleColor(primaryColor, offset)
+		case "next-secondary-color", "previous-secondary-color":
+			offset := 1
+			if action == "previous-secondary-color" {
+				offset = -1
+			}
+			secondaryColor = cycleColor(secondaryColor, offset)
+		case "swap-colors":
+			primaryColor, secondaryColor = secondaryColor, primaryColor
+		case "color-picker", "secondary-color-picker":
+			pickerTarget = &primaryColor
+			if action == "secondary-color-picker" {
+				pickerTarget = &secondaryColor
+			}
+			dialog = newColorPicker(*pickerTarget)
+		case "brush-picker":
+			dialog = newGlyphPicker(brush)
+		case "mirror-axis":
+			if currentSymmetry.mode != "off" {
+				currentSymmetry.axisX, currentSymmetry.axisY = cursorX*2, cursorY*2
+			}
+		case "exit":
+			if selectedArea != nil {
+				selectedArea = nil
 				break
 			}
+			exit(screen)
+		default:
+			for _, tool := range tools {
+				if strings.ToLower(tool) == action {
+					selectTool(tool)
+				}
+			}
+			for _, actionName := range actions {
+				if strings.ToLower(actionName) == action {
+					runAction(actionName)
+				}
+			}
+			for index, attributeName := range attributeBindings {
+				if attributeName == action {
+					selectedAttributes ^= attributes[index]
+				}
+			}
 		}
-		for i := 0; i < len(selectedTool); i++ {
-			setContent(
-				screen,
-				selectedToolOffset+toolsOffset+i,
-				2,
-				'^',
-				tcell.StyleDefault.Foreground(tcell.ColorWhite),
-				false,
-			)
-		}
-		drawRegion(screen, actionsOffset-3, 0, actionsLength+actionsOffset-4, 3, defaultStyle, defaultStyle, ' ', true, false)
-		for action, offset := range actions {
-			for letterOffset, letter := range action {
-				setContent(
-					screen,
-					actionsOffset-2+letterOffset+offset,
-					1,
-					letter,
-					tcell.StyleDefault.Foreground(tcell.ColorWhite),
-					false,
-				)
+	}
+
+	runCommand := func(input string) error {
+		name, argument := splitCommand(input)
+		switch name {
+		case "w":
+			filePath := argument
+			if filePath == "" {
+				filePath = lastFilePath
+			}
+			if filePath == "" {
+				return fmt.Errorf("no file name given")
+			}
+			filePath = resolvePath(".", filePath)
+			format := formatForPath(filePath, saveFormats)
+			if err := saveCanvas(filePath, format); err != nil {
+				return fmt.Errorf("unable to write to file: %v", err.Error())
+			}
+			if format.extension == ".csv" {
+				lastFilePath = filePath
+				savedRevision = canvas.changes()
+			}
+			status.show("Saved " + filepath.Base(filePath))
+		case "e":
+			if argument == "" {
+				return fmt.Errorf("no file name given")
+			}
+			filePath := resolvePath(".", argument)
+			format := formatForPath(filePath, loadFormats)
+			if err := loadCanvas(view, filePath, format); err != nil {
+				return fmt.Errorf("unable to load %v: %v", argument, err.Error())
+			}
+			if format.extension == ".csv" {
+				lastFilePath = filePath
+				savedRevision = canvas.changes()
+			}
+			status.show("Loaded " + filepath.Base(filePath))
+		case "clear":
+			runAction("Clear")
+		case "color", "secondary-color":
+			colorName, ok := parseColorName(argument)
+			if !ok {
+				return fmt.Errorf("unknown color %q", argument)
 			}
+			if name == "color" {
+				primaryColor = colorName
+			} else {
+				secondaryColor = colorName
+			}
+			addRecentColor(colorName)
+		case "tool":
+			tool, ok := toolName(argument)
+			if !ok {
+				return fmt.Errorf("unknown tool %q", argument)
+			}
+			selectTool(tool)
+		case "brush":
+			glyph, ok := parseGlyph(argument)
+			if !ok {
+				return fmt.Errorf("invalid brush %q", argument)
+			}
+			brush = glyph
+		case "palette":
+			if argument == "" {
+				colors = defaultColors
+			} else {
+				palette, err := loadPalette(resolvePath(".", argument))
+				if err != nil {
+					return fmt.Errorf("unable to load %v: %v", argument, err.Error())
+				}
+				paletteFile = resolvePath(".", argument)
+				colors = palette
+			}
+			colorsScroll = 0
+		case "layer":
+			for index, existingLayer := range canvas.layerList() {
+				if existingLayer.name == argument {
+					canvas.setActiveLayer(index)
+					return nil
+				}
+			}
+			return fmt.Errorf("no layer called %v", argument)
+		case "mirror":
+			for _, mode := range symmetryModes {
+				if mode == strings.ToLower(argument) {
+					currentSymmetry.mode = mode
+					if currentSymmetry.axisX == 0 && currentSymmetry.axisY == 0 {
+						x1, y1, x2, y2 := view.bounds()
+						currentSymmetry = centeredSymmetry(mode, x1, y1, x2, y2)
+					}
+					return nil
+				}
+			}
+			return fmt.Errorf("unknown mirror mode %q (use %v)", argument, strings.Join(symmetryModes, ", "))
+		case "grid":
+			switch strings.ToLower(argument) {
+			case "":
+				guides.grid = !guides.grid
+			case "off":
+				guides.grid = false
+			case "on":
+				guides.grid = true
+			default:
+				size, err := strconv.Atoi(argument)
+				if err != nil || size < 1 {
+					return fmt.Errorf("invalid grid size %q", argument)
+				}
+				guides.grid, guides.gridSize = true, size
+			}
+		case "guide":
+			axis, positionText, _ := strings.Cut(argument, " ")
+			position, err := strconv.Atoi(strings.TrimSpace(positionText))
+			if err != nil || position < 0 {
+				return fmt.Errorf("guides look like \"x 40\" or \"y 12\"")
+			}
+			switch strings.ToLower(axis) {
+			case "x":
+				guides.toggleVertical(position)
+			case "y":
+				guides.toggleHorizontal(position + toolbarHeight)
+			default:
+				return fmt.Errorf("unknown guide axis %q (use x or y)", axis)
+			}
+		case "zoom":
+			zoom, ok := zoomIndex(argument)
+			if !ok {
+				return fmt.Errorf("unknown zoom level %q", argument)
+			}
+			view.setZoom(zoom, cursorX, cursorY)
+		case "resize":
+			width, height, err := parseSize(argument)
+			if err != nil {
+				return err
+			}
+			canvas.resize(width, height)
+			broadcast(fmt.Sprintf("resize:%v,%v\n", width, height))
+		case "kick":
+			if argument == "" {
+				return fmt.Errorf("no player given")
+			}
+			if _, err := kick(argument); err != nil {
+				return err
+			}
+		case "q":
+			exit(screen)
+		default:
+			for _, action := range globalBindings {
+				if action == name && action != "command-line" {
+					runBinding(action)
+					return nil
+				}
+			}
+			return fmt.Errorf("unknown command %q", name)
 		}
+		return nil
+	}
+
+	for {
+		screenWidth, screenHeight := screen.Size()
+		width, _ := view.Size()
+
+		bar.layout(screenWidth)
+
+		canvas.draw(view)
+		guides.draw(view)
+		currentSymmetry.draw(view)
+		bar.draw(screen, hoverX, hoverY)
+
+		if (selectedTool == "Line" || selectedTool == "Ellipse" || selectedTool == "Disc") && pressed {
+			previewStyle := paintStyle()
+			previewLetter := brush
+			if erase {
+				previewStyle = tcell.StyleDefault.Background(tcell.ColorGray)
+				previewLetter = ' '
+			}
+			previewPoints := linePoints(startX, startY, lastX, lastY)
+			if selectedTool != "Line" {
+				previewPoints = ellipsePoints(startX, startY, lastX, lastY, selectedTool == "Disc")
+			}
+			if selectedTool == "Line" {
+				withSymmetry(currentSymmetry, func() {
+					drawPreview(view, previewPoints, previewLetter, previewStyle)
+				})
+			} else {
+				drawPreview(view, previewPoints, previewLetter, previewStyle)
+			}
+		}
+		if selectedTool == "Border" && pressed && !erase {
+			drawBorderPreview(view, startX, startY, lastX, lastY, paintStyle(), selectedBorder)
+		}
+		if selectedTool == "Text" && placingText && pressed {
+			newSelection(startX, startY, lastX, lastY).draw(view)
+		}
+		if editingText != nil {
+			editingText.draw(view)
+		} else if keyboardMode && dialog == nil {
+			highlightCell(view, cursorX, cursorY)
+			view.ShowCursor(cursorX, cursorY)
+		} else {
+			screen.HideCursor()
+		}
+		if selectedTool == "Banner" && banner != nil {
+			drawFloatingCells(view, cursorX, cursorY, banner)
+		}
+		if selectedTool == "Stamp" && stamps != nil {
+			if stamps.cells != nil && !stamps.contains(view.screenPoint(cursorX, cursorY)) {
+				drawFloatingCells(view, cursorX, cursorY, stamps.cells)
+			}
+			stamps.draw(screen)
+		}
+		if selectedTool == "Select" && selectedArea != nil {
+			if moving {
+				movedArea := *selectedArea
+				movedArea.x1, movedArea.x2 = movedArea.x1+lastX-startX, movedArea.x2+lastX-startX
+				movedArea.y1, movedArea.y2 = movedArea.y1+lastY-startY, movedArea.y2+lastY-startY
+				drawFloatingCells(view, movedArea.x1, movedArea.y1, movingCells)
+				movedArea.draw(view)
+			} else {
+				selectedArea.draw(view)
+			}
+		}
+		guides.drawRulers(view, cursorX, cursorY)
+		minimapRight := screenWidth - 1
+		if selectedTool == "Stamp" && stamps != nil {
+			minimapRight = stamps.x - 1
+		}
+		overview.layout(screen, view, minimapRight, screenHeight-statusBarHeight-1)
+		overview.draw(screen, view)
+		selectionSize := ""
+		if selectedArea != nil {
+			selectionSize = fmt.Sprintf("%vx%v", selectedArea.x2-selectedArea.x1+1, selectedArea.y2-selectedArea.y1+1)
+		}
+		fileName, modified := "untitled", ""
+		if lastFilePath != "" {
+			fileName = filepath.Base(lastFilePath)
+		}
+		if canvas.changes() != savedRevision {
+			modified = "[+] "
+		}
+		status.draw(screen, []statusField{
+			{fmt.Sprintf("%v, %v", cursorX, cursorY-toolbarHeight), statusStyle},
+			{selectionSize, statusStyle},
+			{toolLabel(selectedTool), statusStyle},
+			{" " + string(brush) + " ", adaptStyle(paintStyle(), screen.Colors())},
+			{primaryColor + " / " + secondaryColor, statusStyle},
+			{view.level().name, statusStyle},
+		}, []statusField{
+			{modified + fileName, statusStyle},
+			{connectionStatus(), statusStyle},
+		})
 		if len(connections) > 0 {
-			for letterOffset, letter := range "Connected to:" {
-				setContent(
-					screen,
-					remainingOffset-2+letterOffset-1,
-					1,
-					letter,
-					tcell.StyleDefault.Foreground(tcell.ColorWhite),
-					false,
-				)
-			}
-			addresses := ""
-			for _, connection := range connections {
-				addresses += connection.RemoteAddr().String() + ", "
-			}
-			for letterOffset, letter := range strings.Trim(addresses, ", ") {
-				setContent(
-					screen,
-					remainingOffset-2+letterOffset-1,
-					2,
-					letter,
-					tcell.StyleDefault.Foreground(tcell.ColorWhite),
-					false,
-				)
+			if message := viewportMessage(view.bounds()); message != lastViewport {
+				broadcast(message)
+				lastViewport = message
 			}
 		}
+		if dialog != nil {
+			dialog.draw(screen)
+		} else if tooltip := bar.tooltip(hoverX, hoverY); tooltip != "" {
+			drawTooltip(screen, hoverX, toolbarHeight, tooltip)
+		}
 
 		screen.Show()
+		flushClipboard()
 		event := screen.PollEvent()
+		handleRemoteMessages(view)
 
-		switch event := event.(type) {
-		case *tcell.EventKey:
-			if event.Key() == tcell.KeyEscape {
-				exit(screen)
-			}
-			if selectedTool == "Text" {
-				if textX >= width || textX <= 0 {
-					textX = 0
-				}
-				if textY >= height || textY <= 0 {
-					textY = 4
+		if _, resized := event.(*tcell.EventResize); dialog != nil && !resized {
+			if dialog.handleEvent(event) {
+				closedDialog := dialog
+				dialog = nil
+				switch closedDialog := closedDialog.(type) {
+				case *colorPicker:
+					if closedDialog.accepted {
+						*pickerTarget = closedDialog.color()
+						addRecentColor(*pickerTarget)
+					}
+				case *glyphPicker:
+					if closedDialog.accepted {
+						brush = closedDialog.glyph()
+					}
+				case *menuDialog:
+					if closedDialog.chosen != nil {
+						closedDialog.chosen.activate()
+					}
+				case *bannerDialog:
+					if closedDialog.accepted {
+						banner = closedDialog.cells()
+					}
+				case *textPrompt:
+					if closedDialog.accepted {
+						closedDialog.onAccept(closedDialog.input)
+					}
+				case *commandLine:
+					if closedDialog.accepted {
+						if err := runCommand(closedDialog.input); err != nil {
+							status.showError(err.Error())
+						}
+					}
+				case *confirmDialog:
+					closedDialog.onClose(closedDialog.accepted)
+				case *fileBrowser:
+					if closedDialog.accepted {
+						closedDialog.onAccept(closedDialog.filePath, closedDialog.selectedFormat())
+					}
+				case *layerDialog:
+					activeLayer := canvas.activeLayer()
+					oldName := activeLayer.name
+					switch closedDialog.action {
+					case "new":
+						dialog = &textPrompt{
+							title: "Layer Name",
+							onAccept: func(name string) {
+								addedLayer, err := canvas.addLayer(name)
+								if err != nil {
+									status.showError("Unable to add layer: " + err.Error())
+									return
+								}
+								broadcastLayer(addedLayer.name)
+							},
+						}
+					case "rename":
+						dialog = &textPrompt{
+							title: "Rename " + oldName,
+							input: oldName,
+							onAccept: func(name string) {
+								if err := canvas.renameLayer(oldName, name); err != nil {
+									status.showError("Unable to rename layer: " + err.Error())
+									return
+								}
+								broadcast(fmt.Sprintf("renameLayer:%v,%v\n", oldName, activeLayer.name))
+							},
+						}
+					case "delete":
+						dialog = &confirmDialog{
+							title:   "Delete Layer",
+							message: "Delete the layer " + oldName + " and everything on it?",
+							onClose: func(accepted bool) {
+								if !accepted {
+									return
+								}
+								if err := canvas.deleteLayer(oldName); err != nil {
+									status.showError("Unable to delete layer: " + err.Error())
+									return
+								}
+								broadcast(fmt.Sprintf("deleteLayer:%v\n", oldName))
+							},
+						}
+					}
 				}
+			}
+			continue
+		}
 
-				if event.Key() == tcell.KeyEnter {
-					textX = 0
-					textY++
-				} else if event.Key() == tcell.KeyBackspace || event.Key() == tcell.KeyBackspace2 {
-					textX--
-					_, _, style, _ := screen.GetContent(textX, textY)
-					_, backgroundColor, _ := style.Decompose()
-					textColor := tcell.StyleDefault.
-						Foreground(backgroundColor).
-						Background(backgroundColor)
-					setContent(screen, textX, textY, ' ', textColor, true)
-				} else {
-					_, _, style, _ := screen.GetContent(textX, textY)
-					originalForegroundColor, originalBackgroundColor, _ := style.Decompose()
-					foregroundColor, backgroundColor := tcell.GetColor(selectedColor), originalBackgroundColor
-					if backgroundColor == 0 {
-						backgroundColor = originalForegroundColor
-					}
-					textColor := tcell.StyleDefault.
-						Foreground(foregroundColor).
-						Background(backgroundColor)
-					setContent(screen, textX, textY, event.Rune(), textColor, true)
-					textX++
+		canvasEvent := false
+		if cursor, ok := event.(*cursorEvent); ok {
+			event, canvasEvent = cursor.EventMouse, true
+		}
+		switch event := event.(type) {
+		case *tcell.EventKey:
+			hoverX, hoverY = -1, -1
+			if editingText != nil {
+				if editingText.handleKey(event) {
+					commitText(view, editingText, true)
+					editingText = nil
 				}
+				break
+			}
+			key := keyName(event)
+			action := ""
+			if keyboardMode {
+				action = boundAction(key, cursorBindings)
+			}
+			if action == "" && selectedTool == "Select" {
+				action = boundAction(key, selectBindings)
+			} else if action == "" && selectedTool == "Stamp" {
+				action = boundAction(key, stampBindings)
+			}
+			if action == "" {
+				action = boundAction(key, globalBindings)
 			}
+			runBinding(action)
 		case *tcell.EventResize:
 			screen.Sync()
 		case *tcell.EventMouse:
 			x, y := event.Position()
+			screenX, screenY := x, y
+			if canvasEvent {
+				screenX, screenY = view.screenPoint(x, y)
+			} else {
+				x, y = view.canvasPoint(x, y)
+			}
+			hoverX, hoverY = screenX, screenY
 			button := event.Buttons()
+			if !canvasEvent && (panning || button == tcell.Button1 && !pressed && overview.contains(screenX, screenY)) {
+				panning = button == tcell.Button1
+				if panning {
+					view.center(overview.canvasPoint(screenX, screenY))
+				}
+				break
+			}
+			if !canvasEvent && status.contains(screenX, screenY) && button != 0 {
+				break
+			}
+			if !canvasEvent && (placingGuide || button == tcell.Button1 && !pressed && guides.rulerContains(screenX, screenY)) {
+				if !placingGuide {
+					guides.handleRulerClick(view, screenX, screenY)
+				}
+				placingGuide = button == tcell.Button1
+				break
+			}
+			if y >= toolbarHeight {
+				cursorX, cursorY = x, y
+			}
+			for _, tool := range mirroredTools {
+				if selectedTool == tool {
+					drawingSymmetry = currentSymmetry
+				}
+			}
+			for _, tool := range snappingTools {
+				if selectedTool == tool && y >= toolbarHeight {
+					x, y = guides.snap(x, y)
+				}
+			}
 			if button == 1 {
-				if y <= 3 {
-					if x < colorsLength+colorsOffset && x-colorsOffset >= 0 {
-						selectedColor = colors[x-colorsOffset]
-					} else if x-toolsOffset < toolsLength-2 && x >= colorsLength+colorsOffset+2 {
-						for tool, offset := range tools {
-							if x-toolsOffset >= offset && x-toolsOffset <= (offset+len(tool)+1) {
-								selectedTool = tool
-								if selectedTool == "Text" {
-									textX, textY = 0, 4
-								}
+				if y < toolbarHeight {
+					bar.handleMouse(screenX, screenY, button)
+				} else {
+					if selectedTool == "Pencil" {
+						if pressed {
+							if runewidth.RuneWidth(brush) == 2 && y == lastY && x-lastX < 2 && lastX-x < 2 {
+								break
 							}
+							drawLine(view, lastX, lastY, x, y, brush, paintStyle(), true)
+						} else {
+							pressed = true
+							drawLine(view, x, y, x, y, brush, paintStyle(), true)
 						}
-					} else if x-actionsOffset < actionsLength-4 && x >= toolsLength {
-						for action, offset := range actions {
-							if x-actionsOffset+2 >= offset && x-actionsOffset+2 <= (offset+len(action)+1) {
-								if action == "Exit" {
-									exit(screen)
-								} else if action == "Clear" {
-									screen.Clear()
-									for _, connection := range connections {
-										go fmt.Fprintf(connection, "clear\n")
-									}
-								} else if action == "Save" {
-									data, _ := dumpData(screen)
-									screen.Suspend()
-
-									reader := bufio.NewScanner(os.Stdin)
-									fmt.Print("(Save) File Path: ")
-									reader.Scan()
-									filePath := reader.Text()
-									if strings.TrimSpace(filePath) == "" {
-										screen.Resume()
-										drawData(string(data), screen)
-										screen.PostEvent(tcell.NewEventResize(width, height))
-										break
-									}
-									err := os.WriteFile(filePath, []byte(data), 0644)
-									if err != nil {
-										fmt.Printf("Unable to write to file: %v\n", err.Error())
-									} else {
-										fmt.Printf("Successfully saved to %v!\n", filePath)
-									}
-
-									fmt.Print("Press Enter to continue...")
-									reader.Scan()
-									screen.Resume()
-									drawData(string(data), screen)
-									screen.PostEvent(tcell.NewEventResize(width, height))
-								} else if action == "Load" {
-									data, _ := dumpData(screen)
-									screen.Suspend()
-
-									reader := bufio.NewScanner(os.Stdin)
-									fmt.Print("(Load) File Path: ")
-									reader.Scan()
-									filePath := reader.Text()
-									if strings.TrimSpace(filePath) == "" {
-										screen.Resume()
-										drawData(string(data), screen)
-										screen.PostEvent(tcell.NewEventResize(width, height))
-										break
-									}
-									fileData, err := os.ReadFile(filePath)
-									if err != nil {
-										fmt.Printf("Unable to load %v: %v\n", filePath, err.Error())
-										fmt.Print("Press Enter to continue...")
-										reader.Scan()
-										screen.Resume()
-										drawData(string(data), screen)
-										screen.PostEvent(tcell.NewEventResize(width, height))
-									} else {
-										screen.Resume()
-										drawData(string(fileData), screen)
-										screen.PostEvent(tcell.NewEventResize(width, height))
-									}
-								}
-							}
+						lastX, lastY = x, y
+					} else if selectedTool == "Line" || selectedTool == "Ellipse" || selectedTool == "Disc" {
+						if !pressed {
+							pressed = true
+							startX = x
+							startY = y
+						}
+						lastX, lastY = x, y
+						if selectedTool != "Line" && (circleMode || event.Modifiers()&(tcell.ModShift|tcell.ModCtrl) != 0) {
+							lastX, lastY = circleEnd(startX, startY, x, y)
 						}
-					}
-				} else {
-					if selectedTool == "Pencil" {
-						setContent(screen, x, y, block, tcell.StyleDefault.Foreground(tcell.GetColor(selectedColor)), true)
 					} else if selectedTool == "Region" {
 						if !pressed {
 							pressed = true
@@ -474,30 +1066,113 @@ func main() {
 							startY = y
 						}
 						if lastX+lastY != 0 {
-							drawRegion(screen, startX, startY, lastX, lastY, defaultStyle, defaultStyle, ' ', false, true)
+							drawRegion(view, startX, startY, lastX, lastY, defaultStyle, defaultStyle, ' ', false, true)
 						}
 						lastX = x
 						lastY = y
-						drawRegion(screen, startX, startY, x, y, tcell.StyleDefault.Foreground(tcell.GetColor(selectedColor)), defaultStyle, block, false, true)
+						drawRegion(view, startX, startY, x, y, paintStyle(), defaultStyle, brush, false, true)
 					} else if selectedTool == "Border" {
 						if !pressed {
 							pressed = true
 							startX = x
 							startY = y
 						}
-						if lastX+lastY != 0 {
-							clearRegion(screen, startX, startY, lastX, lastY, true)
+						lastX, lastY = x, y
+					} else if selectedTool == "Stamp" {
+						if stamps.contains(screenX, screenY) {
+							stamps.handleClick(screenX, screenY)
+						} else if !pressed && stamps.cells != nil {
+							pressed = true
+							pasteCells(view, x, y, stamps.cells, true)
+						}
+					} else if selectedTool == "Banner" {
+						if !pressed && banner != nil {
+							pressed = true
+							pasteCells(view, x, y, banner, true)
+						}
+					} else if selectedTool == "Select" {
+						if !pressed {
+							pressed = true
+							startX = x
+							startY = y
+							if selectedArea != nil && selectedArea.contains(x, y) {
+								moving = true
+								movingCells = copyCells(selectedArea)
+							}
+						}
+						lastX, lastY = x, y
+						if !moving {
+							selectedArea = newSelection(startX, startY, x, y)
+						}
+					} else if selectedTool == "Fill" {
+						if !pressed {
+							pressed = true
+							x1, y1, x2, y2 := view.fillBounds()
+							floodFill(view, x, y, x1, y1, x2, y2, fillConnectivity, fillMatch, fillTolerance, brush, paintStyle(), true)
 						}
-						lastX = x
-						lastY = y
-						drawRegion(screen, startX, startY, x, y, defaultStyle, tcell.StyleDefault.Foreground(tcell.GetColor(selectedColor)), ' ', true, true)
 					} else if selectedTool == "Text" {
-						textX, textY = x, y
+						if !pressed {
+							pressed = true
+							if editingText != nil && editingText.contains(x, y) {
+								editingText.moveCursorTo(x, y)
+							} else {
+								if editingText != nil {
+									commitText(view, editingText, true)
+									editingText = nil
+								}
+								if box := findTextBox(x, y); box != nil {
+									editingText = box.edit(view)
+								} else {
+									placingText = true
+									startX = x
+									startY = y
+								}
+							}
+						}
+						lastX, lastY = x, y
 					}
 				}
 			} else if button == 2 {
-				if selectedTool == "Pencil" {
-					setContent(screen, x, y, ' ', defaultStyle, true)
+				if y < toolbarHeight {
+					bar.handleMouse(screenX, screenY, button)
+				} else if selectedTool == "Pencil" {
+					if pressed {
+						drawLine(view, lastX, lastY, x, y, ' ', defaultStyle, true)
+					} else {
+						pressed = true
+						erase = true
+						drawLine(view, x, y, x, y, ' ', defaultStyle, true)
+					}
+					lastX, lastY = x, y
+				} else if selectedTool == "Line" || selectedTool == "Ellipse" || selectedTool == "Disc" {
+					if !pressed {
+						pressed = true
+						erase = true
+						startX = x
+						startY = y
+					}
+					lastX, lastY = x, y
+					if selectedTool != "Line" && (circleMode || event.Modifiers()&(tcell.ModShift|tcell.ModCtrl) != 0) {
+						lastX, lastY = circleEnd(startX, startY, x, y)
+					}
+				} else if selectedTool == "Select" {
+					if !pressed {
+						pressed = true
+						erase = true
+						cells := loadClipboard()
+						if len(cells) > 0 {
+							pasteCells(view, x, y, cells, true)
+							cellsWidth, cellsHeight := cellsSize(cells)
+							selectedArea = newSelection(x, y, x+cellsWidth-1, y+cellsHeight-1)
+						}
+					}
+				} else if selectedTool == "Fill" {
+					if !pressed {
+						pressed = true
+						erase = true
+						x1, y1, x2, y2 := view.fillBounds()
+						floodFill(view, x, y, x1, y1, x2, y2, fillConnectivity, fillMatch, fillTolerance, ' ', defaultStyle, true)
+					}
 				} else if selectedTool == "Region" {
 					if !pressed {
 						pressed = true
@@ -505,7 +1180,7 @@ func main() {
 						startX = x
 						startY = y
 					}
-					drawRegion(screen, startX, startY, x, y, defaultStyle, defaultStyle, ' ', false, true)
+					drawRegion(view, startX, startY, x, y, defaultStyle, defaultStyle, ' ', false, true)
 				} else if selectedTool == "Border" {
 					if !pressed {
 						pressed = true
@@ -513,22 +1188,76 @@ func main() {
 						startX = x
 						startY = y
 					}
-					drawRegion(screen, startX, startY, x, y, defaultStyle, defaultStyle, ' ', false, true)
+					drawRegion(view, startX, startY, x, y, defaultStyle, defaultStyle, ' ', false, true)
+				}
+			} else if button == tcell.Button3 {
+				if y >= toolbarHeight && currentSymmetry.mode != "off" {
+					currentSymmetry.axisX, currentSymmetry.axisY = x*2, y*2
+				}
+			} else if button == tcell.WheelUp || button == tcell.WheelDown {
+				scrollOffset := 1
+				if button == tcell.WheelUp {
+					scrollOffset = -1
+				}
+				if y < toolbarHeight {
+					bar.handleMouse(screenX, screenY, button)
+				} else if selectedTool == "Stamp" && stamps.contains(screenX, screenY) {
+					stamps.scrollBy(scrollOffset, screenHeight-statusBarHeight)
+				} else if event.Modifiers()&tcell.ModCtrl != 0 {
+					view.setZoom(view.zoom-scrollOffset, x, y)
+				} else if event.Modifiers()&tcell.ModShift != 0 {
+					view.pan(scrollOffset*panMoves["pan-right"].x, 0)
+				} else {
+					view.pan(0, scrollOffset*panMoves["pan-down"].y)
 				}
 			} else if button == 0 {
 				if pressed {
 					pressed = false
+					endX, endY := lastX, lastY
 					lastX, lastY = 0, 0
-					if !erase {
+					letter, style := brush, paintStyle()
+					if erase {
+						letter, style = ' ', defaultStyle
+					}
+					if selectedTool == "Select" && moving {
+						offsetX, offsetY := endX-startX, endY-startY
+						if offsetX != 0 || offsetY != 0 {
+							clearRegion(view, selectedArea.x1, selectedArea.y1, selectedArea.x2, selectedArea.y2, true)
+							pasteCells(view, selectedArea.x1+offsetX, selectedArea.y1+offsetY, movingCells, true)
+							selectedArea = newSelection(
+								selectedArea.x1+offsetX,
+								selectedArea.y1+offsetY,
+								selectedArea.x2+offsetX,
+								selectedArea.y2+offsetY,
+							)
+						}
+						moving = false
+						movingCells = nil
+					} else if selectedTool == "Text" && placingText {
+						if endX == startX && endY == startY {
+							endX = width - 1
+						}
+						editingText = newTextBox(startX, startY, endX, endY, tcell.StyleDefault.
+							Foreground(tcell.GetColor(primaryColor)).
+							Background(tcell.GetColor(secondaryColor)).
+							Attributes(selectedAttributes))
+						placingText = false
+					} else if selectedTool == "Line" {
+						drawLine(view, startX, startY, endX, endY, letter, style, true)
+					} else if selectedTool == "Ellipse" || selectedTool == "Disc" {
+						drawEllipse(view, startX, startY, endX, endY, letter, style, selectedTool == "Disc", true)
+					} else if !erase {
 						if selectedTool == "Region" {
-							drawRegion(screen, startX, startY, x, y, tcell.StyleDefault.Foreground(tcell.GetColor(selectedColor)), defaultStyle, block, false, true)
+							drawRegion(view, startX, startY, x, y, paintStyle(), defaultStyle, brush, false, true)
 						} else if selectedTool == "Border" {
-							drawRegion(screen, startX, startY, x, y, defaultStyle, tcell.StyleDefault.Foreground(tcell.GetColor(selectedColor)), ' ', true, true)
+							drawBorder(view, startX, startY, endX, endY, paintStyle(), selectedBorder, true)
 						}
 					}
+					erase = false
 				}
 			}
 		}
+		drawingSymmetry = symmetry{mode: "off"}
 	}
 }
 
@@ -538,9 +1267,11 @@ func exit(screen tcell.Screen) {
 		connection.Close()
 	}
 
-	data, empty := dumpData(screen)
+	stopAutosave()
+	data, empty := dumpData()
 	screen.Fini()
 	if empty {
+		removeRecovery()
 		os.Exit(0)
 	}
 
@@ -567,7 +1298,7 @@ func exit(screen tcell.Screen) {
 			if strings.TrimSpace(filePath) == "" {
 				continue
 			}
-			err := os.WriteFile(filePath, []byte(data), 0644)
+			err := writeFileAtomic(filePath, []byte(data), 0644)
 			if err != nil {
 				fmt.Printf("Unable to write to file: %v\n", err.Error())
 			} else {
@@ -576,5 +1307,6 @@ func exit(screen tcell.Screen) {
 			}
 		}
 	}
+	removeRecovery()
 	os.Exit(0)
 }
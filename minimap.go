@@ -0,0 +1,216 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+const (
+	minimapWidth  = 32
+	minimapHeight = 10
+)
+
+type minimap struct {
+	visible bool
+	x, y    int
+	width   int
+	height  int
+	scaleX  int
+	scaleY  int
+}
+
+type remoteViewport struct {
+	name           string
+	x1, y1, x2, y2 int
+}
+
+var (
+	sessionID           = strconv.FormatInt(time.Now().UnixNano(), 36)
+	remoteViewports     = make(map[string]remoteViewport)
+	lastViewport        string
+	connectionViewports = make(map[net.Conn]map[string]bool)
+	viewportsMutex      sync.Mutex
+)
+
+func viewportMessage(x1, y1, x2, y2 int) string {
+	return fmt.Sprintf("viewport:%v,%v,%v,%v,%v,%v\n", sessionID, nickname, x1, y1, x2, y2)
+}
+
+func rememberViewport(connection net.Conn, message string) {
+	id, _, found := strings.Cut(strings.TrimPrefix(message, "viewport:"), ",")
+	viewportsMutex.Lock()
+	defer viewportsMutex.Unlock()
+	if !found {
+		delete(connectionViewports[connection], id)
+		return
+	}
+	if connectionViewports[connection] == nil {
+		connectionViewports[connection] = make(map[string]bool)
+	}
+	connectionViewports[connection][id] = true
+}
+
+func forgetViewports(connection net.Conn) {
+	viewportsMutex.Lock()
+	ids := connectionViewports[connection]
+	delete(connectionViewports, connection)
+	viewportsMutex.Unlock()
+
+	for id := range ids {
+		remoteMessages <- "viewport:" + id
+		broadcast("viewport:" + id + "\n")
+	}
+}
+
+func (panel *minimap) contains(x, y int) bool {
+	return panel.visible && x >= panel.x && x < panel.x+panel.width && y >= panel.y && y < panel.y+panel.height
+}
+
+func (panel *minimap) canvasPoint(x, y int) (int, int) {
+	if x < panel.x {
+		x = panel.x
+	} else if x > panel.x+panel.width-1 {
+		x = panel.x + panel.width - 1
+	}
+	if y < panel.y {
+		y = panel.y
+	} else if y > panel.y+panel.height-1 {
+		y = panel.y + panel.height - 1
+	}
+	return (x-panel.x)*panel.scaleX + panel.scaleX/2, toolbarHeight + (y-panel.y)*panel.scaleY*2 + panel.scaleY
+}
+
+func (panel *minimap) screenPoint(x, y int) (int, int) {
+	return panel.x + x/panel.scaleX, panel.y + (y-toolbarHeight)/panel.scaleY/2
+}
+
+func (panel *minimap) layout(screen tcell.Screen, view *canvasView, right, bottom int) {
+	screenWidth, screenHeight := screen.Size()
+	panel.width, panel.height = minimapWidth, minimapHeight
+	if panel.width > screenWidth/3 {
+		panel.width = screenWidth / 3
+	}
+	if panel.height > (screenHeight-toolbarHeight)/3 {
+		panel.height = (screenHeight - toolbarHeight) / 3
+	}
+	if panel.width < 1 {
+		panel.width = 1
+	}
+	if panel.height < 1 {
+		panel.height = 1
+	}
+	panel.x, panel.y = right-panel.width, bottom-panel.height
+
+	_, _, x2, y2 := view.bounds()
+	maximumX, maximumY := x2, y2
+	if width, height := canvas.size(); width != 0 {
+		maximumX, maximumY = width-1, toolbarHeight+height-1
+	} else {
+		for _, existingLayer := range canvas.snapshot() {
+			for cellPoint := range existingLayer.cells {
+				maximumX, maximumY = maxInt(maximumX, cellPoint.x), maxInt(maximumY, cellPoint.y)
+			}
+		}
+		for _, viewport := range remoteViewports {
+			maximumX, maximumY = maxInt(maximumX, viewport.x2), maxInt(maximumY, viewport.y2)
+		}
+	}
+	extentWidth, extentHeight := maximumX+1, maximumY-toolbarHeight+1
+	panel.scaleY = maxInt((extentWidth+panel.width*2-1)/(panel.width*2), (extentHeight+panel.height*2-1)/(panel.height*2))
+	if panel.scaleY < 1 {
+		panel.scaleY = 1
+	}
+	panel.scaleX = panel.scaleY * 2
+}
+
+func (panel *minimap) draw(screen tcell.Screen, view *canvasView) {
+	if !panel.visible {
+		return
+	}
+	frameStyle := tcell.StyleDefault.Foreground(tcell.ColorGray)
+	drawBox(screen, panel.x-1, panel.y-1, panel.x+panel.width, panel.y+panel.height, "Map", frameStyle)
+
+	pixels := make(map[point]tcell.Color)
+	for cellPoint, existingCell := range compositeCells(canvas.snapshot()) {
+		color, filled := sampleColor(existingCell.character, existingCell.style)
+		if !filled {
+			continue
+		}
+		pixel := point{cellPoint.x / panel.scaleX, (cellPoint.y - toolbarHeight) / panel.scaleY}
+		if pixel.x < 0 || pixel.y < 0 || pixel.x >= panel.width || pixel.y >= panel.height*2 {
+			continue
+		}
+		pixels[pixel] = color
+	}
+	colorCount := screen.Colors()
+	for row := 0; row < panel.height; row++ {
+		for col := 0; col < panel.width; col++ {
+			top, topFilled := pixels[point{col, row * 2}]
+			bottom, bottomFilled := pixels[point{col, row*2 + 1}]
+			style := tcell.StyleDefault
+			character := ' '
+			switch {
+			case topFilled && bottomFilled:
+				character, style = '▀', style.Foreground(top).Background(bottom)
+			case topFilled:
+				character, style = '▀', style.Foreground(top)
+			case bottomFilled:
+				character, style = '▄', style.Foreground(bottom)
+			}
+			screen.SetContent(panel.x+col, panel.y+row, character, nil, adaptStyle(style, colorCount))
+		}
+	}
+
+	for _, viewport := range remoteViewports {
+		panel.drawViewport(screen, viewport.x1, viewport.y1, viewport.x2, viewport.y2, tcell.StyleDefault.Foreground(tcell.ColorAqua))
+	}
+	x1, y1, x2, y2 := view.bounds()
+	panel.drawViewport(screen, x1, y1, x2, y2, tcell.StyleDefault.Foreground(tcell.ColorYellow))
+}
+
+func (panel *minimap) drawViewport(screen tcell.Screen, x1, y1, x2, y2 int, style tcell.Style) {
+	left, top := panel.screenPoint(x1, y1)
+	right, bottom := panel.screenPoint(x2, y2)
+	setCell := func(x, y int, character rune) {
+		if x < panel.x || y < panel.y || x >= panel.x+panel.width || y >= panel.y+panel.height {
+			return
+		}
+		_, _, cellStyle, _ := screen.GetContent(x, y)
+		_, backgroundColor, _ := cellStyle.Decompose()
+		screen.SetContent(x, y, character, nil, style.Background(backgroundColor))
+	}
+	if left == right && top == bottom {
+		setCell(left, top, '□')
+		return
+	}
+	for x := left + 1; x < right; x++ {
+		setCell(x, top, '─')
+		setCell(x, bottom, '─')
+	}
+	for y := top + 1; y < bottom; y++ {
+		setCell(left, y, '│')
+		setCell(right, y, '│')
+	}
+	if left == right {
+		setCell(left, top, '╷')
+		setCell(left, bottom, '╵')
+		return
+	}
+	if top == bottom {
+		setCell(left, top, '╶')
+		setCell(right, top, '╴')
+		return
+	}
+	setCell(left, top, '┌')
+	setCell(right, top, '┐')
+	setCell(left, bottom, '└')
+	setCell(right, bottom, '┘')
+}
+
+var overview = &minimap{}
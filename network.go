@@ -7,6 +7,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/gdamore/tcell/v2"
 )
@@ -25,36 +26,129 @@ func removeConnection(array []net.Conn, connection net.Conn) []net.Conn {
 	}
 }
 
+var (
+	remoteMessages = make(chan string, 1024)
+	nicknames      = make(map[net.Conn]string)
+	nicknamesMutex sync.Mutex
+)
+
+func connectionName(connection net.Conn) string {
+	nicknamesMutex.Lock()
+	defer nicknamesMutex.Unlock()
+	if name, ok := nicknames[connection]; ok {
+		return name
+	}
+	return connection.RemoteAddr().String()
+}
+
+func forgetNickname(connection net.Conn) {
+	nicknamesMutex.Lock()
+	defer nicknamesMutex.Unlock()
+	delete(nicknames, connection)
+}
+
+func kick(name string) (int, error) {
+	if !hostServer {
+		return 0, fmt.Errorf("only the host can kick players")
+	}
+	kicked := 0
+	for _, connection := range connections {
+		if connectionName(connection) == name || connection.RemoteAddr().String() == name {
+			fmt.Fprintf(connection, "exit\n")
+			connection.Close()
+			kicked++
+		}
+	}
+	if kicked == 0 {
+		return 0, fmt.Errorf("no player called %v", name)
+	}
+	return kicked, nil
+}
+
+func connectionNames() []string {
+	names := make([]string, len(connections))
+	for index, connection := range connections {
+		names[index] = connectionName(connection)
+	}
+	return names
+}
+
+func connectionStatus() string {
+	switch {
+	case hostServer && len(connections) == 1:
+		return fmt.Sprintf("hosting on port %v (1 player)", port)
+	case hostServer:
+		return fmt.Sprintf("hosting on port %v (%v players)", port, len(connections))
+	case connectAddress != "" && len(connections) > 0:
+		return "connected to " + connectAddress
+	case connectAddress != "":
+		return "disconnected"
+	}
+	return "offline"
+}
+
+func broadcast(message string) {
+	for _, connection := range connections {
+		go fmt.Fprint(connection, message)
+	}
+}
+
+func broadcastOnLayer(message string) {
+	activeLayer := canvas.activeLayer()
+	if activeLayer.locked {
+		return
+	}
+	broadcast("@" + activeLayer.name + "," + drawingSymmetry.format(message))
+}
+
+func broadcastLayer(name string) {
+	for index, existingLayer := range canvas.layerList() {
+		if existingLayer.name == name {
+			broadcast(fmt.Sprintf("layer:%v,%v,%v,%v\n", existingLayer.name, index, existingLayer.visible, existingLayer.locked))
+		}
+	}
+}
+
+func dumpMessages() string {
+	messages := ""
+	if width, height := canvas.size(); width != 0 {
+		messages += fmt.Sprintf("resize:%v,%v\n", width, height)
+	}
+	for index, existingLayer := range canvas.snapshot() {
+		messages += fmt.Sprintf("layer:%v,%v,%v,%v\n", existingLayer.name, index, existingLayer.visible, existingLayer.locked)
+		for _, cellPoint := range existingLayer.points() {
+			existingCell := existingLayer.cells[cellPoint]
+			if formattedCell := formatCell(cellPoint.x, cellPoint.y, existingCell.character, existingCell.style); formattedCell != "" {
+				messages += "@" + existingLayer.name + ",set:" + formattedCell
+			}
+		}
+	}
+	return messages
+}
+
 func handleConnections(listener net.Listener, screen tcell.Screen) {
 	for {
 		connection, _ := listener.Accept()
-		data, empty := dumpData(screen)
-		var newData string
-		if !empty {
-			lines := strings.Split(data, "\n")
-			for index, line := range lines {
-				if index == 0 || strings.TrimSpace(line) == "" {
-					continue
-				}
-				newData += "set:" + line + "\n"
-			}
-		}
-		fmt.Fprintf(connection, newData)
+		fmt.Fprint(connection, dumpMessages())
 		go handleConnection(connection, screen)
 	}
 }
 
 func handleConnection(connection net.Conn, screen tcell.Screen) {
 	connections = append(connections, connection)
+	if nickname != "" {
+		fmt.Fprintf(connection, "nickname:%v\n", nickname)
+	}
 	reader := bufio.NewReader(connection)
 	for {
 		width, height := screen.Size()
-		screen.PostEvent(tcell.NewEventResize(width, height))
 
 		rawMessage, err := reader.ReadString('\n')
 		if err != nil {
 			connection.Close()
 			connections = removeConnection(connections, connection)
+			forgetNickname(connection)
+			forgetViewports(connection)
 			screen.PostEvent(tcell.NewEventResize(width, height))
 			return
 		}
@@ -62,9 +156,25 @@ func handleConnection(connection net.Conn, screen tcell.Screen) {
 		if message == "exit" {
 			connection.Close()
 			connections = removeConnection(connections, connection)
+			forgetNickname(connection)
+			forgetViewports(connection)
 			screen.PostEvent(tcell.NewEventResize(width, height))
 			return
 		}
+		if strings.HasPrefix(message, "nickname:") {
+			name := strings.TrimPrefix(message, "nickname:")
+			if validateNickname(name) != nil {
+				continue
+			}
+			nicknamesMutex.Lock()
+			nicknames[connection] = name
+			nicknamesMutex.Unlock()
+			screen.PostEvent(tcell.NewEventResize(width, height))
+			continue
+		}
+		if strings.HasPrefix(message, "viewport:") {
+			rememberViewport(connection, message)
+		}
 
 		for _, existingConnection := range connections {
 			if existingConnection != connection {
@@ -72,99 +182,295 @@ func handleConnection(connection net.Conn, screen tcell.Screen) {
 			}
 		}
 
-		if strings.HasPrefix(message, "set:") {
-			segments := strings.Split(strings.Split(message, "set:")[1], ",")
-			x, err := strconv.Atoi(segments[0])
-			if err != nil {
-				screen.Fini()
-				fmt.Println("Invalid X coordinate received")
-				os.Exit(1)
-			}
-			y, err := strconv.Atoi(segments[1])
-			if err != nil {
-				screen.Fini()
-				fmt.Println("Invalid Y coordinate received")
-				os.Exit(1)
-			}
-			character := ' '
-			if strings.HasSuffix(message, ",,") {
-				character = ','
-			} else {
-				characters := []rune(segments[4])
-				if len(characters) > 0 {
-					character = characters[0]
-				}
-			}
-			textColor := tcell.StyleDefault.
-				Foreground(tcell.GetColor(segments[2])).
-				Background(tcell.GetColor(segments[3]))
-			screen.SetContent(x, y, character, nil, textColor)
-		} else if strings.HasPrefix(message, "region:") {
-			segments := strings.Split(strings.Split(message, "region:")[1], ",")
-			x1, err := strconv.Atoi(segments[0])
-			if err != nil {
-				screen.Fini()
-				fmt.Println("Invalid X1 coordinate received")
-				os.Exit(1)
-			}
-			y1, err := strconv.Atoi(segments[1])
-			if err != nil {
-				screen.Fini()
-				fmt.Println("Invalid Y1 coordinate received")
-				os.Exit(1)
-			}
-			x2, err := strconv.Atoi(segments[2])
+		remoteMessages <- message
+		screen.PostEvent(tcell.NewEventInterrupt(nil))
+	}
+}
+
+func handleRemoteMessages(screen tcell.Screen) {
+	for {
+		select {
+		case message := <-remoteMessages:
+			handleMessage(screen, message)
+		default:
+			return
+		}
+	}
+}
+
+func splitLayerName(message string) (string, string) {
+	if !strings.HasPrefix(message, "@") {
+		return "", message
+	}
+	name, message, _ := strings.Cut(message[1:], ",")
+	return name, message
+}
+
+func exitInvalidMessage(screen tcell.Screen, message string) {
+	stopAutosave()
+	screen.Fini()
+	fmt.Println(message)
+	os.Exit(1)
+}
+
+func handleMessage(screen tcell.Screen, message string) {
+	var err error
+	layerName, message := splitLayerName(message)
+	canvas.setTarget(layerName)
+	defer canvas.clearTarget()
+
+	if strings.HasPrefix(message, "mirror:") {
+		segments := strings.SplitN(strings.Split(message, "mirror:")[1], ",", 4)
+		if len(segments) < 4 {
+			exitInvalidMessage(screen, "Invalid mirror received")
+		}
+		axisX, err := strconv.Atoi(segments[1])
+		if err != nil {
+			exitInvalidMessage(screen, "Invalid mirror axis received")
+		}
+		axisY, err := strconv.Atoi(segments[2])
+		if err != nil {
+			exitInvalidMessage(screen, "Invalid mirror axis received")
+		}
+		drawingSymmetry = symmetry{segments[0], axisX, axisY}
+		defer func() {
+			drawingSymmetry = symmetry{mode: "off"}
+		}()
+		message = segments[3]
+	}
+
+	if strings.HasPrefix(message, "set:") {
+		segments := strings.Split(strings.Split(message, "set:")[1], ",")
+		x, err := strconv.Atoi(segments[0])
+		if err != nil {
+			exitInvalidMessage(screen, "Invalid X coordinate received")
+		}
+		y, err := strconv.Atoi(segments[1])
+		if err != nil {
+			exitInvalidMessage(screen, "Invalid Y coordinate received")
+		}
+		if len(segments) < 6 {
+			exitInvalidMessage(screen, "Invalid cell received")
+		}
+		character, textColor := parseCell(message, segments, true)
+		setContent(screen, x, y, character, textColor, false)
+	} else if strings.HasPrefix(message, "region:") {
+		segments := strings.Split(strings.Split(message, "region:")[1], ",")
+		x1, err := strconv.Atoi(segments[0])
+		if err != nil {
+			exitInvalidMessage(screen, "Invalid X1 coordinate received")
+		}
+		y1, err := strconv.Atoi(segments[1])
+		if err != nil {
+			exitInvalidMessage(screen, "Invalid Y1 coordinate received")
+		}
+		x2, err := strconv.Atoi(segments[2])
+		if err != nil {
+			exitInvalidMessage(screen, "Invalid X2 coordinate received")
+		}
+		y2, err := strconv.Atoi(segments[3])
+		if err != nil {
+			exitInvalidMessage(screen, "Invalid Y2 coordinate received")
+		}
+		textColor := tcell.StyleDefault.
+			Foreground(tcell.GetColor(segments[4])).
+			Background(tcell.GetColor(segments[5]))
+		borderStyle := tcell.StyleDefault.
+			Foreground(tcell.GetColor(segments[6])).
+			Background(tcell.GetColor(segments[7]))
+		drawBorders := false
+		if segments[9] == "true" {
+			drawBorders = true
+		}
+		drawRegion(screen, x1, y1, x2, y2, textColor, borderStyle, []rune(segments[8])[0], drawBorders, false)
+	} else if strings.HasPrefix(message, "line:") {
+		segments := strings.Split(strings.Split(message, "line:")[1], ",")
+		if len(segments) < 8 {
+			exitInvalidMessage(screen, "Invalid line received")
+		}
+		coordinates := make([]int, 4)
+		for index := range coordinates {
+			coordinates[index], err = strconv.Atoi(segments[index])
 			if err != nil {
-				screen.Fini()
-				fmt.Println("Invalid X2 coordinate received")
-				os.Exit(1)
+				exitInvalidMessage(screen, "Invalid line coordinate received")
 			}
-			y2, err := strconv.Atoi(segments[3])
+		}
+		letter, style := parseCell(message, segments[2:], true)
+		drawLine(screen, coordinates[0], coordinates[1], coordinates[2], coordinates[3], letter, style, false)
+	} else if strings.HasPrefix(message, "ellipse:") {
+		segments := strings.Split(strings.Split(message, "ellipse:")[1], ",")
+		if len(segments) < 9 {
+			exitInvalidMessage(screen, "Invalid ellipse received")
+		}
+		coordinates := make([]int, 4)
+		for index := range coordinates {
+			coordinates[index], err = strconv.Atoi(segments[index])
 			if err != nil {
-				screen.Fini()
-				fmt.Println("Invalid Y2 coordinate received")
-				os.Exit(1)
-			}
-			textColor := tcell.StyleDefault.
-				Foreground(tcell.GetColor(segments[4])).
-				Background(tcell.GetColor(segments[5]))
-			borderStyle := tcell.StyleDefault.
-				Foreground(tcell.GetColor(segments[6])).
-				Background(tcell.GetColor(segments[7]))
-			drawBorders := false
-			if segments[9] == "true" {
-				drawBorders = true
+				exitInvalidMessage(screen, "Invalid ellipse coordinate received")
 			}
-			drawRegion(screen, x1, y1, x2, y2, textColor, borderStyle, []rune(segments[8])[0], drawBorders, false)
-		} else if strings.HasPrefix(message, "clearRegion:") {
-			segments := strings.Split(strings.Split(message, "clearRegion:")[1], ",")
-			x1, err := strconv.Atoi(segments[0])
+		}
+		letter, style := parseCell(message, segments[3:], true)
+		drawEllipse(screen, coordinates[0], coordinates[1], coordinates[2], coordinates[3], letter, style, segments[4] == "true", false)
+	} else if strings.HasPrefix(message, "border:") {
+		segments := strings.Split(strings.Split(message, "border:")[1], ",")
+		if len(segments) < 8 {
+			exitInvalidMessage(screen, "Invalid border received")
+		}
+		coordinates := make([]int, 4)
+		for index := range coordinates {
+			coordinates[index], err = strconv.Atoi(segments[index])
 			if err != nil {
-				screen.Fini()
-				fmt.Println("Invalid X1 coordinate received")
-				os.Exit(1)
+				exitInvalidMessage(screen, "Invalid border coordinate received")
 			}
-			y1, err := strconv.Atoi(segments[1])
+		}
+		_, style := parseCell(message, segments[3:], true)
+		drawBorder(screen, coordinates[0], coordinates[1], coordinates[2], coordinates[3], style, segments[4], false)
+	} else if strings.HasPrefix(message, "text:") {
+		segments := strings.SplitN(strings.Split(message, "text:")[1], ",", 10)
+		if len(segments) < 10 {
+			exitInvalidMessage(screen, "Invalid text received")
+		}
+		coordinates := make([]int, 4)
+		for index := range coordinates {
+			coordinates[index], err = strconv.Atoi(segments[index+1])
 			if err != nil {
-				screen.Fini()
-				fmt.Println("Invalid Y1 coordinate received")
-				os.Exit(1)
+				exitInvalidMessage(screen, "Invalid text coordinate received")
 			}
-			x2, err := strconv.Atoi(segments[2])
+		}
+		_, style := parseCell(message, segments[4:], true)
+		box := newTextBox(coordinates[0], coordinates[1], coordinates[2], coordinates[3], style)
+		box.id = segments[0]
+		box.alignment = segments[5]
+		box.text = []rune(unescapeText(segments[9]))
+		commitText(screen, box, false)
+	} else if strings.HasPrefix(message, "fill:") {
+		segments := strings.Split(strings.Split(message, "fill:")[1], ",")
+		if len(segments) < 13 {
+			exitInvalidMessage(screen, "Invalid fill received")
+		}
+		values := make([]int, 7)
+		for index := range values {
+			values[index], err = strconv.Atoi(segments[index])
 			if err != nil {
-				screen.Fini()
-				fmt.Println("Invalid X2 coordinate received")
-				os.Exit(1)
+				exitInvalidMessage(screen, "Invalid fill coordinate received")
 			}
-			y2, err := strconv.Atoi(segments[3])
+		}
+		tolerance, err := strconv.Atoi(segments[8])
+		if err != nil {
+			exitInvalidMessage(screen, "Invalid fill tolerance received")
+		}
+		letter, style := parseCell(message, segments[7:], true)
+		floodFill(
+			screen,
+			values[0],
+			values[1],
+			values[2],
+			values[3],
+			values[4],
+			values[5],
+			values[6],
+			segments[7],
+			tolerance,
+			letter,
+			style,
+			false,
+		)
+	} else if strings.HasPrefix(message, "paste:") {
+		segments := strings.SplitN(strings.Split(message, "paste:")[1], ",", 3)
+		if len(segments) < 3 {
+			exitInvalidMessage(screen, "Invalid paste received")
+		}
+		x, err := strconv.Atoi(segments[0])
+		if err != nil {
+			exitInvalidMessage(screen, "Invalid X coordinate received")
+		}
+		y, err := strconv.Atoi(segments[1])
+		if err != nil {
+			exitInvalidMessage(screen, "Invalid Y coordinate received")
+		}
+		cells, err := parsePastedCells(segments[2])
+		if err != nil {
+			exitInvalidMessage(screen, "Invalid pasted cells received")
+		}
+		pasteCells(screen, x, y, cells, false)
+	} else if strings.HasPrefix(message, "clearRegion:") {
+		segments := strings.Split(strings.Split(message, "clearRegion:")[1], ",")
+		x1, err := strconv.Atoi(segments[0])
+		if err != nil {
+			exitInvalidMessage(screen, "Invalid X1 coordinate received")
+		}
+		y1, err := strconv.Atoi(segments[1])
+		if err != nil {
+			exitInvalidMessage(screen, "Invalid Y1 coordinate received")
+		}
+		x2, err := strconv.Atoi(segments[2])
+		if err != nil {
+			exitInvalidMessage(screen, "Invalid X2 coordinate received")
+		}
+		y2, err := strconv.Atoi(segments[3])
+		if err != nil {
+			exitInvalidMessage(screen, "Invalid Y2 coordinate received")
+		}
+		clearRegion(screen, x1, y1, x2, y2, false)
+	} else if strings.HasPrefix(message, "layer:") {
+		segments := strings.Split(strings.Split(message, "layer:")[1], ",")
+		if len(segments) < 4 {
+			exitInvalidMessage(screen, "Invalid layer received")
+		}
+		index, err := strconv.Atoi(segments[1])
+		if err != nil {
+			exitInvalidMessage(screen, "Invalid layer index received")
+		}
+		canvas.updateLayer(segments[0], index, segments[2] == "true", segments[3] == "true")
+	} else if strings.HasPrefix(message, "renameLayer:") {
+		segments := strings.Split(strings.Split(message, "renameLayer:")[1], ",")
+		if len(segments) < 2 {
+			exitInvalidMessage(screen, "Invalid layer name received")
+		}
+		canvas.renameLayer(segments[0], segments[1])
+	} else if strings.HasPrefix(message, "deleteLayer:") {
+		canvas.deleteLayer(strings.Split(message, "deleteLayer:")[1])
+	} else if strings.HasPrefix(message, "resize:") {
+		segments := strings.Split(strings.Split(message, "resize:")[1], ",")
+		if len(segments) < 2 {
+			exitInvalidMessage(screen, "Invalid size received")
+		}
+		width, err := strconv.Atoi(segments[0])
+		if err != nil || width < 0 {
+			exitInvalidMessage(screen, "Invalid width received")
+		}
+		height, err := strconv.Atoi(segments[1])
+		if err != nil || height < 0 {
+			exitInvalidMessage(screen, "Invalid height received")
+		}
+		canvas.resize(width, height)
+	} else if strings.HasPrefix(message, "viewport:") {
+		segments := strings.Split(strings.Split(message, "viewport:")[1], ",")
+		if len(segments) == 1 {
+			delete(remoteViewports, segments[0])
+			return
+		}
+		if len(segments) < 6 {
+			exitInvalidMessage(screen, "Invalid viewport received")
+		}
+		coordinates := make([]int, 4)
+		for index := range coordinates {
+			coordinates[index], err = strconv.Atoi(segments[len(segments)-4+index])
 			if err != nil {
-				screen.Fini()
-				fmt.Println("Invalid Y2 coordinate received")
-				os.Exit(1)
+				exitInvalidMessage(screen, "Invalid viewport coordinate received")
 			}
-			clearRegion(screen, x1, y1, x2, y2, false)
-		} else if message == "clear" {
-			screen.Clear()
 		}
+		if _, ok := remoteViewports[segments[0]]; !ok {
+			lastViewport = ""
+		}
+		remoteViewports[segments[0]] = remoteViewport{
+			strings.Join(segments[1:len(segments)-4], ","),
+			coordinates[0],
+			coordinates[1],
+			coordinates[2],
+			coordinates[3],
+		}
+	} else if message == "clear" {
+		canvas.clear()
 	}
 }
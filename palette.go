@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+func loadPalette(filePath string) ([]string, error) {
+	fileData, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return parsePalette(string(fileData))
+}
+
+func parsePalette(data string) ([]string, error) {
+	lines := strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n")
+	var palette []string
+	var err error
+	if strings.TrimSpace(lines[0]) == "GIMP Palette" {
+		palette, err = parseGimpPalette(lines)
+	} else if strings.TrimSpace(lines[0]) == "JASC-PAL" {
+		palette, err = parseJascPalette(lines)
+	} else {
+		palette, err = parseHexPalette(lines)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(palette) == 0 {
+		return nil, fmt.Errorf("no colors found")
+	}
+	return palette, nil
+}
+
+func parseGimpPalette(lines []string) ([]string, error) {
+	var palette []string
+	for index, line := range lines[1:] {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.Contains(line, ":") {
+			continue
+		}
+		color, err := parseRGBFields(strings.Fields(line))
+		if err != nil {
+			return nil, fmt.Errorf("invalid color at line %v", index+2)
+		}
+		palette = append(palette, color)
+	}
+	return palette, nil
+}
+
+func parseJascPalette(lines []string) ([]string, error) {
+	var palette []string
+	for index, line := range lines {
+		line = strings.TrimSpace(line)
+		if index < 3 || line == "" {
+			continue
+		}
+		color, err := parseRGBFields(strings.Fields(line))
+		if err != nil {
+			return nil, fmt.Errorf("invalid color at line %v", index+1)
+		}
+		palette = append(palette, color)
+	}
+	return palette, nil
+}
+
+func parseHexPalette(lines []string) ([]string, error) {
+	var palette []string
+	for index, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, ";") || strings.HasPrefix(line, "//") {
+			continue
+		}
+		for _, field := range strings.FieldsFunc(line, func(letter rune) bool {
+			return letter == ',' || letter == ' ' || letter == '\t'
+		}) {
+			field = strings.TrimPrefix(strings.TrimPrefix(field, "#"), "0x")
+			if len(field) == 8 {
+				field = field[2:]
+			}
+			if len(field) != 6 {
+				return nil, fmt.Errorf("invalid color at line %v", index+1)
+			}
+			if _, err := strconv.ParseUint(field, 16, 32); err != nil {
+				return nil, fmt.Errorf("invalid color at line %v", index+1)
+			}
+			palette = append(palette, "#"+strings.ToUpper(field))
+		}
+	}
+	return palette, nil
+}
+
+func parseRGBFields(fields []string) (string, error) {
+	if len(fields) < 3 {
+		return "", fmt.Errorf("expected 3 color components")
+	}
+	var components [3]int
+	for index := range components {
+		component, err := strconv.Atoi(fields[index])
+		if err != nil {
+			return "", err
+		}
+		if component < 0 || component > 255 {
+			return "", fmt.Errorf("color component out of range")
+		}
+		components[index] = component
+	}
+	return fmt.Sprintf("#%02X%02X%02X", components[0], components[1], components[2]), nil
+}
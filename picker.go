@@ -0,0 +1,262 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+const (
+	pickerWidth       = 46
+	pickerHeight      = 12
+	sliderWidth       = 32
+	maxRecentColors   = 8
+	pickerOkLabel     = "[ OK ]"
+	pickerCancelLabel = "[ Cancel ]"
+)
+
+var (
+	pickerChannels = []string{"R", "G", "B", "H", "S", "V"}
+	recentColors   []string
+)
+
+type colorPicker struct {
+	red, green, blue       int
+	hue, saturation, value float64
+	selectedChannel        int
+	hexInput               string
+	editingHex             bool
+	accepted               bool
+	x, y                   int
+}
+
+func newColorPicker(colorName string) *colorPicker {
+	picker := &colorPicker{}
+	red, green, blue := tcell.GetColor(colorName).RGB()
+	if red < 0 || green < 0 || blue < 0 {
+		red, green, blue = 255, 255, 255
+	}
+	picker.setRGB(int(red), int(green), int(blue))
+	return picker
+}
+
+func addRecentColor(colorName string) {
+	for index, existingColor := range recentColors {
+		if existingColor == colorName {
+			recentColors = append(recentColors[:index], recentColors[index+1:]...)
+			break
+		}
+	}
+	recentColors = append([]string{colorName}, recentColors...)
+	if len(recentColors) > maxRecentColors {
+		recentColors = recentColors[:maxRecentColors]
+	}
+}
+
+func (picker *colorPicker) color() string {
+	return fmt.Sprintf("#%02X%02X%02X", picker.red, picker.green, picker.blue)
+}
+
+func (picker *colorPicker) setRGB(red, green, blue int) {
+	picker.red, picker.green, picker.blue = red, green, blue
+	hue, saturation, value := colorful.Color{
+		R: float64(red) / 255,
+		G: float64(green) / 255,
+		B: float64(blue) / 255,
+	}.Hsv()
+	if saturation > 0 && value > 0 {
+		picker.hue = hue
+	}
+	if value > 0 {
+		picker.saturation = saturation
+	}
+	picker.value = value
+}
+
+func (picker *colorPicker) setHSV(hue, saturation, value float64) {
+	picker.hue, picker.saturation, picker.value = hue, saturation, value
+	red, green, blue := colorful.Hsv(hue, saturation, value).Clamped().RGB255()
+	picker.red, picker.green, picker.blue = int(red), int(green), int(blue)
+}
+
+func (picker *colorPicker) channelFraction(channel int) float64 {
+	switch pickerChannels[channel] {
+	case "R":
+		return float64(picker.red) / 255
+	case "G":
+		return float64(picker.green) / 255
+	case "B":
+		return float64(picker.blue) / 255
+	case "H":
+		return picker.hue / 360
+	case "S":
+		return picker.saturation
+	default:
+		return picker.value
+	}
+}
+
+func (picker *colorPicker) channelValue(channel int) int {
+	switch pickerChannels[channel] {
+	case "R":
+		return picker.red
+	case "G":
+		return picker.green
+	case "B":
+		return picker.blue
+	case "H":
+		return int(math.Round(picker.hue))
+	case "S":
+		return int(math.Round(picker.saturation * 100))
+	default:
+		return int(math.Round(picker.value * 100))
+	}
+}
+
+func (picker *colorPicker) setChannel(channel int, fraction float64) {
+	fraction = math.Max(0, math.Min(1, fraction))
+	switch pickerChannels[channel] {
+	case "R":
+		picker.setRGB(int(math.Round(fraction*255)), picker.green, picker.blue)
+	case "G":
+		picker.setRGB(picker.red, int(math.Round(fraction*255)), picker.blue)
+	case "B":
+		picker.setRGB(picker.red, picker.green, int(math.Round(fraction*255)))
+	case "H":
+		picker.setHSV(fraction*360, picker.saturation, picker.value)
+	case "S":
+		picker.setHSV(picker.hue, fraction, picker.value)
+	default:
+		picker.setHSV(picker.hue, picker.saturation, fraction)
+	}
+}
+
+func (picker *colorPicker) channelStep(channel int) float64 {
+	switch pickerChannels[channel] {
+	case "R", "G", "B":
+		return 1.0 / 255
+	case "H":
+		return 1.0 / 360
+	default:
+		return 0.01
+	}
+}
+
+func (picker *colorPicker) previewColor(channel int, fraction float64) tcell.Color {
+	preview := *picker
+	preview.setChannel(channel, fraction)
+	return tcell.NewRGBColor(int32(preview.red), int32(preview.green), int32(preview.blue))
+}
+
+func (picker *colorPicker) draw(screen tcell.Screen) {
+	picker.x, picker.y = centeredBox(screen, pickerWidth, pickerHeight)
+
+	colorCount := screen.Colors()
+	defaultStyle := tcell.StyleDefault.Foreground(tcell.ColorWhite)
+	drawBox(screen, picker.x, picker.y, picker.x+pickerWidth-1, picker.y+pickerHeight-1, "Color Picker", defaultStyle)
+	for channel, label := range pickerChannels {
+		row := picker.y + 1 + channel
+		labelStyle := defaultStyle
+		if channel == picker.selectedChannel {
+			labelStyle = labelStyle.Reverse(true)
+		}
+		drawText(screen, picker.x+2, row, label, labelStyle)
+
+		marker := int(math.Round(picker.channelFraction(channel) * (sliderWidth - 1)))
+		for offset := 0; offset < sliderWidth; offset++ {
+			sliderColor := picker.previewColor(channel, float64(offset)/(sliderWidth-1))
+			style := tcell.StyleDefault.Background(sliderColor)
+			letter := ' '
+			if offset == marker {
+				letter = '┃'
+				style = style.Foreground(contrastingColor(sliderColor))
+			}
+			screen.SetContent(picker.x+4+offset, row, letter, nil, adaptStyle(style, colorCount))
+		}
+		drawText(screen, picker.x+5+sliderWidth, row, fmt.Sprintf("%3d", picker.channelValue(channel)), defaultStyle)
+	}
+
+	hexText := picker.color()
+	if picker.editingHex {
+		hexText = "#" + picker.hexInput + strings.Repeat("_", 6-len(picker.hexInput))
+	}
+	drawText(screen, picker.x+2, picker.y+8, "Hex "+hexText, defaultStyle)
+	previewStyle := adaptStyle(tcell.StyleDefault.Foreground(tcell.GetColor(picker.color())), colorCount)
+	for offset := 0; offset < 8; offset++ {
+		screen.SetContent(picker.x+16+offset, picker.y+8, block, nil, previewStyle)
+	}
+	drawText(screen, picker.x+2, picker.y+10, pickerOkLabel, defaultStyle)
+	drawText(screen, picker.x+4+len(pickerOkLabel), picker.y+10, pickerCancelLabel, defaultStyle)
+}
+
+func (picker *colorPicker) handleEvent(event tcell.Event) bool {
+	switch event := event.(type) {
+	case *tcell.EventKey:
+		switch event.Key() {
+		case tcell.KeyEscape:
+			return true
+		case tcell.KeyEnter:
+			picker.accepted = true
+			return true
+		case tcell.KeyUp:
+			picker.selectedChannel = (picker.selectedChannel + len(pickerChannels) - 1) % len(pickerChannels)
+		case tcell.KeyDown:
+			picker.selectedChannel = (picker.selectedChannel + 1) % len(pickerChannels)
+		case tcell.KeyLeft:
+			picker.setChannel(picker.selectedChannel, picker.channelFraction(picker.selectedChannel)-picker.channelStep(picker.selectedChannel))
+		case tcell.KeyRight:
+			picker.setChannel(picker.selectedChannel, picker.channelFraction(picker.selectedChannel)+picker.channelStep(picker.selectedChannel))
+		case tcell.KeyBackspace, tcell.KeyBackspace2:
+			if len(picker.hexInput) > 0 {
+				picker.hexInput = picker.hexInput[:len(picker.hexInput)-1]
+			}
+		case tcell.KeyRune:
+			letter := event.Rune()
+			if letter == '#' {
+				picker.editingHex = true
+				picker.hexInput = ""
+			} else if strings.ContainsRune("0123456789abcdefABCDEF", letter) {
+				picker.editingHex = true
+				picker.hexInput += strings.ToUpper(string(letter))
+				if len(picker.hexInput) == 6 {
+					value, _ := strconv.ParseInt(picker.hexInput, 16, 32)
+					picker.setRGB(int(value>>16&0xFF), int(value>>8&0xFF), int(value&0xFF))
+					picker.editingHex = false
+					picker.hexInput = ""
+				}
+			}
+		}
+	case *tcell.EventMouse:
+		if event.Buttons() != tcell.Button1 {
+			return false
+		}
+		x, y := event.Position()
+		channel := y - picker.y - 1
+		if channel >= 0 && channel < len(pickerChannels) && x >= picker.x+4 && x < picker.x+4+sliderWidth {
+			picker.selectedChannel = channel
+			picker.setChannel(channel, float64(x-picker.x-4)/(sliderWidth-1))
+		} else if y == picker.y+10 {
+			okOffset := picker.x + 2
+			cancelOffset := picker.x + 4 + len(pickerOkLabel)
+			if x >= okOffset && x < okOffset+len(pickerOkLabel) {
+				picker.accepted = true
+				return true
+			} else if x >= cancelOffset && x < cancelOffset+len(pickerCancelLabel) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func contrastingColor(color tcell.Color) tcell.Color {
+	red, green, blue := color.RGB()
+	if 0.299*float64(red)+0.587*float64(green)+0.114*float64(blue) > 128 {
+		return tcell.ColorBlack
+	}
+	return tcell.ColorWhite
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+type adaptedColorKey struct {
+	color      tcell.Color
+	colorCount int
+}
+
+var (
+	adaptedColors      = make(map[adaptedColorKey]tcell.Color)
+	adaptedColorsMutex sync.Mutex
+)
+
+func adaptStyle(style tcell.Style, colorCount int) tcell.Style {
+	foregroundColor, backgroundColor, _ := style.Decompose()
+	return style.
+		Foreground(adaptColor(foregroundColor, colorCount)).
+		Background(adaptColor(backgroundColor, colorCount))
+}
+
+func adaptColor(color tcell.Color, colorCount int) tcell.Color {
+	if !color.IsRGB() || colorCount >= 1<<24 || colorCount < 8 {
+		return color
+	}
+
+	adaptedColorsMutex.Lock()
+	defer adaptedColorsMutex.Unlock()
+
+	key := adaptedColorKey{color, colorCount}
+	if adaptedColor, ok := adaptedColors[key]; ok {
+		return adaptedColor
+	}
+	firstIndex, lastIndex := 0, colorCount
+	if colorCount >= 256 {
+		// the first 16 colors are themed by the terminal, so only the fixed
+		// color cube and grayscale ramp give the same result everywhere
+		firstIndex, lastIndex = 16, 256
+	} else if colorCount > 16 {
+		lastIndex = 16
+	}
+	adaptedColor := nearestPaletteColor(color, firstIndex, lastIndex)
+	adaptedColors[key] = adaptedColor
+	return adaptedColor
+}
+
+func nearestPaletteColor(color tcell.Color, firstIndex, lastIndex int) tcell.Color {
+	target := toColorful(color)
+	nearestColor := color
+	nearestDistance := -1.0
+	for index := firstIndex; index < lastIndex; index++ {
+		paletteColor := tcell.PaletteColor(index)
+		distance := target.DistanceCIEDE2000(toColorful(paletteColor))
+		if nearestDistance < 0 || distance < nearestDistance {
+			nearestColor = paletteColor
+			nearestDistance = distance
+		}
+	}
+	return nearestColor
+}
+
+func toColorful(color tcell.Color) colorful.Color {
+	red, green, blue := color.RGB()
+	return colorful.Color{
+		R: float64(red) / 255,
+		G: float64(green) / 255,
+		B: float64(blue) / 255,
+	}
+}
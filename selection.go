@@ -0,0 +1,223 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+type selection struct {
+	x1, y1, x2, y2 int
+}
+
+var (
+	clipboard        map[point]cell
+	pendingClipboard string
+)
+
+func newSelection(x1, y1, x2, y2 int) *selection {
+	if y2 < y1 {
+		y1, y2 = y2, y1
+	}
+	if x2 < x1 {
+		x1, x2 = x2, x1
+	}
+	if y1 < toolbarHeight {
+		y1 = toolbarHeight
+	}
+	return &selection{x1, y1, x2, y2}
+}
+
+func (area *selection) contains(x, y int) bool {
+	return x >= area.x1 && x <= area.x2 && y >= area.y1 && y <= area.y2
+}
+
+func (area *selection) draw(screen tcell.Screen) {
+	for col := area.x1; col <= area.x2; col++ {
+		highlightCell(screen, col, area.y1)
+		if area.y2 != area.y1 {
+			highlightCell(screen, col, area.y2)
+		}
+	}
+	for row := area.y1 + 1; row < area.y2; row++ {
+		highlightCell(screen, area.x1, row)
+		if area.x2 != area.x1 {
+			highlightCell(screen, area.x2, row)
+		}
+	}
+}
+
+func highlightCell(screen tcell.Screen, x, y int) {
+	if view, ok := screen.(*canvasView); ok {
+		view.highlight(x, y)
+		return
+	}
+	character, combiningCharacters, style, _ := screen.GetContent(x, y)
+	screen.SetContent(x, y, character, combiningCharacters, style.Reverse(true))
+}
+
+func copyCells(area *selection) map[point]cell {
+	cells := make(map[point]cell)
+	for row := area.y1; row <= area.y2; row++ {
+		for col := area.x1; col <= area.x2; col++ {
+			character, style, ok := canvas.get(col, row)
+			if ok {
+				cells[point{col - area.x1, row - area.y1}] = cell{character, style}
+			}
+		}
+	}
+	return cells
+}
+
+func sortedCellPoints(cells map[point]cell) []point {
+	points := make([]point, 0, len(cells))
+	for cellPoint := range cells {
+		points = append(points, cellPoint)
+	}
+	sort.Slice(points, func(i, j int) bool {
+		if points[i].y == points[j].y {
+			return points[i].x < points[j].x
+		}
+		return points[i].y < points[j].y
+	})
+	return points
+}
+
+func cellsSize(cells map[point]cell) (int, int) {
+	width, height := 0, 0
+	for cellPoint := range cells {
+		if cellPoint.x+1 > width {
+			width = cellPoint.x + 1
+		}
+		if cellPoint.y+1 > height {
+			height = cellPoint.y + 1
+		}
+	}
+	return width, height
+}
+
+func clipboardPath() (string, error) {
+	cacheDirectory, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDirectory, "termcanvas", "clipboard.csv"), nil
+}
+
+func setClipboard(cells map[point]cell) {
+	clipboard = cells
+
+	if filePath, err := clipboardPath(); err == nil {
+		if os.MkdirAll(filepath.Dir(filePath), 0755) == nil {
+			writeFileAtomic(filePath, []byte(formatCells(cells)), 0644)
+		}
+	}
+
+	width, height := cellsSize(cells)
+	lines := make([]string, height)
+	for row := 0; row < height; row++ {
+		line := ""
+		for col := 0; col < width; col++ {
+			existingCell, ok := cells[point{col, row}]
+			if !ok {
+				line += " "
+			} else if existingCell.character != 0 {
+				line += string(existingCell.character)
+			}
+		}
+		lines[row] = strings.TrimRight(line, " ")
+	}
+	pendingClipboard = fmt.Sprintf("\x1b]52;c;%v\a", base64.StdEncoding.EncodeToString([]byte(strings.Join(lines, "\n"))))
+}
+
+func flushClipboard() {
+	if pendingClipboard != "" {
+		fmt.Fprint(os.Stdout, pendingClipboard)
+		pendingClipboard = ""
+	}
+}
+
+func loadClipboard() map[point]cell {
+	filePath, err := clipboardPath()
+	if err != nil {
+		return clipboard
+	}
+	fileData, err := os.ReadFile(filePath)
+	if err != nil {
+		return clipboard
+	}
+	cells, err := parseCells(string(fileData))
+	if err != nil {
+		return clipboard
+	}
+	return cells
+}
+
+func pasteCells(screen tcell.Screen, x, y int, cells map[point]cell, send bool) {
+	formattedCells := []string{}
+	for _, cellPoint := range sortedCellPoints(cells) {
+		pastedCell := cells[cellPoint]
+		if y+cellPoint.y >= toolbarHeight {
+			setContent(screen, x+cellPoint.x, y+cellPoint.y, pastedCell.character, pastedCell.style, false)
+		}
+		foregroundColorName, backgroundColorName, attributes := formatStyle(pastedCell.style)
+		formattedCells = append(formattedCells, fmt.Sprintf(
+			"%v:%v:%v:%v:%v:%v",
+			cellPoint.x,
+			cellPoint.y,
+			foregroundColorName,
+			backgroundColorName,
+			attributes,
+			pastedCell.character,
+		))
+	}
+	if len(connections) > 0 && send {
+		broadcastOnLayer(fmt.Sprintf("paste:%v,%v,%v\n", x, y, strings.Join(formattedCells, ";")))
+	}
+}
+
+func parsePastedCells(formattedCells string) (map[point]cell, error) {
+	cells := make(map[point]cell)
+	if formattedCells == "" {
+		return cells, nil
+	}
+	for _, formattedCell := range strings.Split(formattedCells, ";") {
+		segments := strings.Split(formattedCell, ":")
+		if len(segments) != 6 {
+			return nil, fmt.Errorf("invalid cell %v", formattedCell)
+		}
+		x, err := strconv.Atoi(segments[0])
+		if err != nil {
+			return nil, err
+		}
+		y, err := strconv.Atoi(segments[1])
+		if err != nil {
+			return nil, err
+		}
+		character, err := strconv.Atoi(segments[5])
+		if err != nil {
+			return nil, err
+		}
+		style := tcell.StyleDefault.
+			Foreground(tcell.GetColor(segments[2])).
+			Background(tcell.GetColor(segments[3])).
+			Attributes(parseAttributes(segments[4]))
+		cells[point{x, y}] = cell{rune(character), style}
+	}
+	return cells, nil
+}
+
+func drawFloatingCells(screen tcell.Screen, x, y int, cells map[point]cell) {
+	colorCount := screen.Colors()
+	for cellPoint, floatingCell := range cells {
+		if y+cellPoint.y >= toolbarHeight && floatingCell.character != 0 {
+			screen.SetContent(x+cellPoint.x, y+cellPoint.y, floatingCell.character, nil, adaptStyle(floatingCell.style, colorCount))
+		}
+	}
+}
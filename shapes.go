@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+)
+
+const cellAspectRatio = 2
+
+func linePoints(x1, y1, x2, y2 int) []point {
+	deltaX, deltaY := x2-x1, y2-y1
+	stepX, stepY := 1, 1
+	if deltaX < 0 {
+		deltaX, stepX = -deltaX, -1
+	}
+	if deltaY < 0 {
+		deltaY, stepY = -deltaY, -1
+	}
+
+	points := []point{}
+	x, y := x1, y1
+	difference := deltaX - deltaY
+	for {
+		points = append(points, point{x, y})
+		if x == x2 && y == y2 {
+			return points
+		}
+		doubledDifference := difference * 2
+		if doubledDifference > -deltaY {
+			difference -= deltaY
+			x += stepX
+		}
+		if doubledDifference < deltaX {
+			difference += deltaX
+			y += stepY
+		}
+	}
+}
+
+func drawPoints(screen tcell.Screen, points []point, letter rune, style tcell.Style) {
+	letterWidth := runewidth.RuneWidth(letter)
+	for _, activeMirror := range drawingSymmetry.mirrors() {
+		mirroredPoints := drawingSymmetry.mirrorPoints(points, activeMirror, letter)
+		mirroredLetter := mirrorLetter(letter, activeMirror)
+		var lastPoint *point
+		for index, linePoint := range mirroredPoints {
+			if linePoint.y < toolbarHeight {
+				continue
+			}
+			if letterWidth == 2 && lastPoint != nil && lastPoint.y == linePoint.y &&
+				linePoint.x-lastPoint.x < 2 && lastPoint.x-linePoint.x < 2 {
+				continue
+			}
+			setContent(screen, linePoint.x, linePoint.y, mirroredLetter, style, false)
+			lastPoint = &mirroredPoints[index]
+		}
+	}
+}
+
+func drawLine(screen tcell.Screen, x1, y1, x2, y2 int, letter rune, style tcell.Style, send bool) {
+	drawPoints(screen, linePoints(x1, y1, x2, y2), letter, style)
+	if len(connections) > 0 && send {
+		foregroundColorName, backgroundColorName, attributes := formatStyle(style)
+		broadcastOnLayer(fmt.Sprintf(
+			"line:%v,%v,%v,%v,%v,%v,%v,%v\n",
+			x1,
+			y1,
+			x2,
+			y2,
+			foregroundColorName,
+			backgroundColorName,
+			attributes,
+			string(letter),
+		))
+	}
+}
+
+func drawPreview(screen tcell.Screen, points []point, letter rune, style tcell.Style) {
+	style = adaptStyle(style, screen.Colors())
+	for _, activeMirror := range drawingSymmetry.mirrors() {
+		mirroredLetter := mirrorLetter(letter, activeMirror)
+		for _, previewPoint := range drawingSymmetry.mirrorPoints(points, activeMirror, letter) {
+			if previewPoint.y >= toolbarHeight {
+				screen.SetContent(previewPoint.x, previewPoint.y, mirroredLetter, nil, style)
+			}
+		}
+	}
+}
+
+func toolLabel(tool string) string {
+	if circleMode && (tool == "Ellipse" || tool == "Disc") {
+		return tool + " (circles)"
+	}
+	return tool
+}
+
+func circleEnd(x1, y1, x2, y2 int) (int, int) {
+	deltaX, deltaY := x2-x1, y2-y1
+	stepX, stepY := 1, 1
+	if deltaX < 0 {
+		deltaX, stepX = -deltaX, -1
+	}
+	if deltaY < 0 {
+		deltaY, stepY = -deltaY, -1
+	}
+	radius := deltaY
+	if (deltaX+1)/cellAspectRatio > radius {
+		radius = (deltaX + 1) / cellAspectRatio
+	}
+	return x1 + stepX*radius*cellAspectRatio, y1 + stepY*radius
+}
+
+func ellipsePoints(x1, y1, x2, y2 int, filled bool) []point {
+	if y2 < y1 {
+		y1, y2 = y2, y1
+	}
+	if x2 < x1 {
+		x1, x2 = x2, x1
+	}
+
+	centerX, centerY := float64(x1+x2)/2, float64(y1+y2)/2
+	radiusX, radiusY := float64(x2-x1)/2+0.5, float64(y2-y1)/2+0.5
+	spans := make([][2]int, y2-y1+1)
+	for row := range spans {
+		distanceY := (float64(y1+row) - centerY) / radiusY
+		halfWidth := radiusX * math.Sqrt(math.Max(0, 1-distanceY*distanceY))
+		left := int(math.Ceil(centerX - halfWidth - 0.5))
+		right := int(math.Floor(centerX + halfWidth + 0.5))
+		if left < x1 {
+			left = x1
+		}
+		if right > x2 {
+			right = x2
+		}
+		if right < left {
+			left, right = int(centerX), int(centerX)
+		}
+		spans[row] = [2]int{left, right}
+	}
+
+	points := []point{}
+	for row, span := range spans {
+		if filled || row == 0 || row == len(spans)-1 {
+			for col := span[0]; col <= span[1]; col++ {
+				points = append(points, point{col, y1 + row})
+			}
+			continue
+		}
+		leftEnd, rightStart := span[0], span[1]
+		for _, neighbor := range []int{row - 1, row + 1} {
+			if spans[neighbor][0]-1 > leftEnd {
+				leftEnd = spans[neighbor][0] - 1
+			}
+			if spans[neighbor][1]+1 < rightStart {
+				rightStart = spans[neighbor][1] + 1
+			}
+		}
+		if leftEnd >= rightStart {
+			leftEnd, rightStart = span[1], span[1]+1
+		}
+		for col := span[0]; col <= leftEnd; col++ {
+			points = append(points, point{col, y1 + row})
+		}
+		for col := rightStart; col <= span[1]; col++ {
+			points = append(points, point{col, y1 + row})
+		}
+	}
+	return points
+}
+
+func drawEllipse(screen tcell.Screen, x1, y1, x2, y2 int, letter rune, style tcell.Style, filled bool, send bool) {
+	drawPoints(screen, ellipsePoints(x1, y1, x2, y2, filled), letter, style)
+	if len(connections) > 0 && send {
+		foregroundColorName, backgroundColorName, attributes := formatStyle(style)
+		broadcastOnLayer(fmt.Sprintf(
+			"ellipse:%v,%v,%v,%v,%v,%v,%v,%v,%v\n",
+			x1,
+			y1,
+			x2,
+			y2,
+			filled,
+			foregroundColorName,
+			backgroundColorName,
+			attributes,
+			string(letter),
+		))
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+const (
+	stampPanelWidth    = 26
+	stampPreviewHeight = 5
+	stampEntryHeight   = stampPreviewHeight + 2
+)
+
+type stampLibrary struct {
+	names    []string
+	stamps   map[string]map[point]cell
+	selected int
+	scroll   int
+	cells    map[point]cell
+	x        int
+}
+
+func dataDirectory() (string, error) {
+	if directory := os.Getenv("XDG_DATA_HOME"); directory != "" {
+		return directory, nil
+	}
+	homeDirectory, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDirectory, ".local", "share"), nil
+}
+
+func stampsDirectory() (string, error) {
+	directory, err := dataDirectory()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(directory, "termcanvas", "stamps"), nil
+}
+
+func loadStampLibrary() *stampLibrary {
+	library := &stampLibrary{stamps: make(map[string]map[point]cell)}
+	directory, err := stampsDirectory()
+	if err != nil {
+		return library
+	}
+	filePaths, _ := filepath.Glob(filepath.Join(directory, "*.csv"))
+	sort.Strings(filePaths)
+	for _, filePath := range filePaths {
+		fileData, err := os.ReadFile(filePath)
+		if err != nil {
+			continue
+		}
+		cells, err := parseCells(string(fileData))
+		if err != nil || len(cells) == 0 {
+			continue
+		}
+		name := strings.TrimSuffix(filepath.Base(filePath), ".csv")
+		library.names = append(library.names, name)
+		library.stamps[name] = normalizeCells(cells)
+	}
+	library.selectStamp(0)
+	return library
+}
+
+func saveStamp(name string, cells map[point]cell) error {
+	name = strings.TrimSuffix(strings.TrimSpace(name), ".csv")
+	if name == "" || strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return fmt.Errorf("invalid stamp name")
+	}
+	if len(cells) == 0 {
+		return fmt.Errorf("the selection is empty")
+	}
+	directory, err := stampsDirectory()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(directory, name+".csv"), []byte(formatCells(normalizeCells(cells))), 0644)
+}
+
+func (library *stampLibrary) selectStamp(index int) {
+	if index < 0 || index >= len(library.names) {
+		library.cells = nil
+		return
+	}
+	library.selected = index
+	library.cells = library.stamps[library.names[index]]
+}
+
+func (library *stampLibrary) deleteSelected() error {
+	if library.selected >= len(library.names) {
+		return nil
+	}
+	directory, err := stampsDirectory()
+	if err != nil {
+		return err
+	}
+	name := library.names[library.selected]
+	if err := os.Remove(filepath.Join(directory, name+".csv")); err != nil {
+		return err
+	}
+	delete(library.stamps, name)
+	library.names = append(library.names[:library.selected], library.names[library.selected+1:]...)
+	if library.selected >= len(library.names) {
+		library.selected = len(library.names) - 1
+	}
+	library.selectStamp(library.selected)
+	return nil
+}
+
+func (library *stampLibrary) contains(x, y int) bool {
+	return x >= library.x && y >= toolbarHeight
+}
+
+func (library *stampLibrary) visibleEntries(height int) int {
+	visibleEntries := (height - toolbarHeight - 2) / stampEntryHeight
+	if visibleEntries < 1 {
+		visibleEntries = 1
+	}
+	return visibleEntries
+}
+
+func (library *stampLibrary) scrollBy(offset, height int) {
+	library.scroll += offset
+	if library.scroll > len(library.names)-library.visibleEntries(height) {
+		library.scroll = len(library.names) - library.visibleEntries(height)
+	}
+	if library.scroll < 0 {
+		library.scroll = 0
+	}
+}
+
+func (library *stampLibrary) draw(screen tcell.Screen) {
+	width, height := screen.Size()
+	height -= statusBarHeight
+	library.x = width - stampPanelWidth
+	defaultStyle := tcell.StyleDefault.Foreground(tcell.ColorWhite)
+	drawBox(screen, library.x, toolbarHeight, width-1, height-1, "Stamps", defaultStyle)
+	if len(library.names) == 0 {
+		drawText(screen, library.x+2, toolbarHeight+1, "No stamps yet!", defaultStyle)
+		drawText(screen, library.x+2, toolbarHeight+3, "Select something with", defaultStyle)
+		drawText(screen, library.x+2, toolbarHeight+4, "the Select tool and", defaultStyle)
+		drawText(screen, library.x+2, toolbarHeight+5, "press ctrl+s to save", defaultStyle)
+		drawText(screen, library.x+2, toolbarHeight+6, "it as a stamp.", defaultStyle)
+		return
+	}
+
+	colorCount := screen.Colors()
+	for entry := 0; entry < library.visibleEntries(height); entry++ {
+		index := library.scroll + entry
+		if index >= len(library.names) {
+			break
+		}
+		y := toolbarHeight + 1 + entry*stampEntryHeight
+		nameStyle := defaultStyle
+		if index == library.selected {
+			nameStyle = nameStyle.Reverse(true)
+		}
+		name := library.names[index]
+		if len(name) > stampPanelWidth-4 {
+			name = name[:stampPanelWidth-4]
+		}
+		drawText(screen, library.x+2, y, name, nameStyle)
+		for cellPoint, stampCell := range library.stamps[library.names[index]] {
+			if cellPoint.x >= stampPanelWidth-4 || cellPoint.y >= stampPreviewHeight || stampCell.character == 0 {
+				continue
+			}
+			screen.SetContent(library.x+2+cellPoint.x, y+1+cellPoint.y, stampCell.character, nil, adaptStyle(stampCell.style, colorCount))
+		}
+	}
+	if library.scroll > 0 {
+		screen.SetContent(width-2, toolbarHeight+1, '▲', nil, defaultStyle)
+	}
+	if library.scroll+library.visibleEntries(height) < len(library.names) {
+		screen.SetContent(width-2, height-2, '▼', nil, defaultStyle)
+	}
+}
+
+func (library *stampLibrary) handleClick(x, y int) {
+	index := library.scroll + (y-toolbarHeight-1)/stampEntryHeight
+	if y > toolbarHeight && index < len(library.names) {
+		library.selectStamp(index)
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+)
+
+const (
+	statusBarHeight = 1
+	statusDuration  = 4 * time.Second
+)
+
+type statusField struct {
+	text  string
+	style tcell.Style
+}
+
+type statusBar struct {
+	screen       tcell.Screen
+	message      string
+	messageStyle tcell.Style
+	expires      time.Time
+}
+
+var statusStyle = tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.ColorSilver)
+
+func newStatusBar(screen tcell.Screen) *statusBar {
+	return &statusBar{screen: screen}
+}
+
+func (bar *statusBar) show(message string) {
+	bar.showStyled(message, statusStyle.Bold(true))
+}
+
+func (bar *statusBar) showError(message string) {
+	bar.showStyled(message, tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorMaroon).Bold(true))
+}
+
+func (bar *statusBar) showStyled(message string, style tcell.Style) {
+	bar.message, bar.messageStyle = message, style
+	bar.expires = time.Now().Add(statusDuration)
+	time.AfterFunc(statusDuration, func() {
+		bar.screen.PostEvent(tcell.NewEventInterrupt(nil))
+	})
+}
+
+func (bar *statusBar) contains(x, y int) bool {
+	_, height := bar.screen.Size()
+	return y >= height-statusBarHeight
+}
+
+func visibleStatusFields(fields []statusField) []statusField {
+	var visibleFields []statusField
+	for _, field := range fields {
+		if field.text != "" {
+			visibleFields = append(visibleFields, field)
+		}
+	}
+	return visibleFields
+}
+
+func drawStatusFields(screen tcell.Screen, x, y, maximumX int, fields []statusField) int {
+	for index, field := range fields {
+		if index > 0 {
+			x = drawStatusText(screen, x, y, maximumX, " │ ", statusStyle)
+		}
+		x = drawStatusText(screen, x, y, maximumX, field.text, field.style)
+	}
+	return x
+}
+
+func drawStatusText(screen tcell.Screen, x, y, maximumX int, text string, style tcell.Style) int {
+	for _, letter := range text {
+		letterWidth := runewidth.RuneWidth(letter)
+		if x+letterWidth > maximumX {
+			break
+		}
+		screen.SetContent(x, y, letter, nil, style)
+		x += letterWidth
+	}
+	return x
+}
+
+func statusFieldsWidth(fields []statusField) int {
+	width := 0
+	for index, field := range fields {
+		if index > 0 {
+			width += 3
+		}
+		width += runewidth.StringWidth(field.text)
+	}
+	return width
+}
+
+func (bar *statusBar) draw(screen tcell.Screen, left, right []statusField) {
+	width, height := screen.Size()
+	y := height - statusBarHeight
+	for x := 0; x < width; x++ {
+		screen.SetContent(x, y, ' ', nil, statusStyle)
+	}
+	left, right = visibleStatusFields(left), visibleStatusFields(right)
+
+	for len(right) > 0 && statusFieldsWidth(right)+2 > width/2 {
+		right = right[1:]
+	}
+	rightX := width - statusFieldsWidth(right) - 1
+	drawStatusFields(screen, rightX, y, width, right)
+
+	if time.Now().Before(bar.expires) {
+		drawStatusText(screen, 1, y, rightX-1, " "+bar.message+" ", bar.messageStyle)
+		return
+	}
+	drawStatusFields(screen, 1, y, rightX-1, left)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+)
+
+type symmetry struct {
+	mode         string
+	axisX, axisY int
+}
+
+type mirror struct {
+	flipX, flipY bool
+}
+
+var (
+	symmetryModes   = []string{"off", "horizontal", "vertical", "4-way"}
+	currentSymmetry = symmetry{mode: "off"}
+	drawingSymmetry = symmetry{mode: "off"}
+)
+
+func withSymmetry(activeSymmetry symmetry, draw func()) {
+	drawingSymmetry = activeSymmetry
+	defer func() {
+		drawingSymmetry = symmetry{mode: "off"}
+	}()
+	draw()
+}
+
+func (activeSymmetry symmetry) mirrors() []mirror {
+	mirrors := []mirror{{false, false}}
+	if activeSymmetry.mode == "horizontal" || activeSymmetry.mode == "4-way" {
+		mirrors = append(mirrors, mirror{true, false})
+	}
+	if activeSymmetry.mode == "vertical" || activeSymmetry.mode == "4-way" {
+		mirrors = append(mirrors, mirror{false, true})
+	}
+	if activeSymmetry.mode == "4-way" {
+		mirrors = append(mirrors, mirror{true, true})
+	}
+	return mirrors
+}
+
+func (activeSymmetry symmetry) mirrorPoint(original point, activeMirror mirror, letter rune) point {
+	if activeMirror.flipX {
+		original.x = activeSymmetry.axisX - original.x
+		if runewidth.RuneWidth(letter) == 2 {
+			original.x--
+		}
+	}
+	if activeMirror.flipY {
+		original.y = activeSymmetry.axisY - original.y
+	}
+	return original
+}
+
+func (activeSymmetry symmetry) mirrorPoints(points []point, activeMirror mirror, letter rune) []point {
+	mirroredPoints := make([]point, len(points))
+	for index, original := range points {
+		mirroredPoints[index] = activeSymmetry.mirrorPoint(original, activeMirror, letter)
+	}
+	return mirroredPoints
+}
+
+func mirrorLetter(letter rune, activeMirror mirror) rune {
+	if activeMirror.flipX {
+		letter = remapRune(letter, horizontalMirrors)
+	}
+	if activeMirror.flipY {
+		letter = remapRune(letter, verticalMirrors)
+	}
+	return letter
+}
+
+func (activeSymmetry symmetry) format(message string) string {
+	if activeSymmetry.mode == "off" {
+		return message
+	}
+	return fmt.Sprintf("mirror:%v,%v,%v,%v", activeSymmetry.mode, activeSymmetry.axisX, activeSymmetry.axisY, message)
+}
+
+func centeredSymmetry(mode string, x1, y1, x2, y2 int) symmetry {
+	return symmetry{mode, x1 + x2, y1 + y2}
+}
+
+func (activeSymmetry symmetry) draw(view *canvasView) {
+	if activeSymmetry.mode == "off" {
+		return
+	}
+	x1, y1, x2, y2 := view.bounds()
+	axisStyle := tcell.StyleDefault.Foreground(tcell.ColorGray)
+	drawAxisCell := func(x, y int, letter rune) {
+		if character, _, _, _ := view.GetContent(x, y); character == ' ' {
+			view.SetContent(x, y, letter, nil, axisStyle)
+		}
+	}
+	if activeSymmetry.mode != "vertical" {
+		for row := y1; row <= y2; row++ {
+			drawAxisCell(activeSymmetry.axisX/2, row, '┊')
+			if activeSymmetry.axisX%2 != 0 {
+				drawAxisCell(activeSymmetry.axisX/2+1, row, '┊')
+			}
+		}
+	}
+	if activeSymmetry.mode != "horizontal" {
+		for col := x1; col <= x2; col++ {
+			drawAxisCell(col, activeSymmetry.axisY/2, '┈')
+			if activeSymmetry.axisY%2 != 0 {
+				drawAxisCell(col, activeSymmetry.axisY/2+1, '┈')
+			}
+		}
+	}
+}
@@ -0,0 +1,352 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+)
+
+type textLine struct {
+	start, end int
+}
+
+type textBox struct {
+	id             string
+	x1, y1, x2, y2 int
+	alignment      string
+	style          tcell.Style
+	text           []rune
+	cursor         int
+	layer          string
+	backdrop       map[point]cell
+	rendered       map[point]cell
+}
+
+var (
+	textAlignment = "left"
+	textBoxes     = make(map[string]*textBox)
+)
+
+func newTextBox(x1, y1, x2, y2 int, style tcell.Style) *textBox {
+	if y2 < y1 {
+		y1, y2 = y2, y1
+	}
+	if x2 < x1 {
+		x1, x2 = x2, x1
+	}
+	if y1 < toolbarHeight {
+		y1 = toolbarHeight
+	}
+	return &textBox{
+		id:        fmt.Sprintf("%x", time.Now().UnixNano()),
+		x1:        x1,
+		y1:        y1,
+		x2:        x2,
+		y2:        y2,
+		alignment: textAlignment,
+		style:     style,
+		layer:     canvas.drawingLayerName(),
+	}
+}
+
+func findTextBox(x, y int) *textBox {
+	activeLayerName := canvas.activeLayer().name
+	for _, box := range textBoxes {
+		if box.layer == activeLayerName && box.contains(x, y) {
+			return box
+		}
+	}
+	return nil
+}
+
+func wrapText(text []rune, width int) []textLine {
+	lines := []textLine{}
+	for start := 0; start <= len(text); {
+		end := start
+		for end < len(text) && text[end] != '\n' {
+			end++
+		}
+		lineStart := start
+		for {
+			lineWidth, breakIndex, index := 0, -1, lineStart
+			for ; index < end; index++ {
+				letterWidth := runewidth.RuneWidth(text[index])
+				if lineWidth+letterWidth > width {
+					break
+				}
+				if text[index] == ' ' {
+					breakIndex = index
+				}
+				lineWidth += letterWidth
+			}
+			if index >= end {
+				lines = append(lines, textLine{lineStart, end})
+				break
+			}
+			if text[index] == ' ' {
+				lines = append(lines, textLine{lineStart, index})
+				lineStart = index + 1
+			} else if breakIndex > lineStart {
+				lines = append(lines, textLine{lineStart, breakIndex})
+				lineStart = breakIndex + 1
+			} else {
+				if index == lineStart {
+					index++
+				}
+				lines = append(lines, textLine{lineStart, index})
+				lineStart = index
+			}
+		}
+		start = end + 1
+	}
+	return lines
+}
+
+func textWidth(text []rune) int {
+	width := 0
+	for _, letter := range text {
+		width += runewidth.RuneWidth(letter)
+	}
+	return width
+}
+
+func escapeText(text string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(text)
+}
+
+func unescapeText(text string) string {
+	return strings.NewReplacer(`\\`, `\`, `\n`, "\n").Replace(text)
+}
+
+func (box *textBox) lines() []textLine {
+	return wrapText(box.text, box.x2-box.x1+1)
+}
+
+func (box *textBox) bottom(lines []textLine) int {
+	if box.y1+len(lines)-1 > box.y2 {
+		return box.y1 + len(lines) - 1
+	}
+	return box.y2
+}
+
+func (box *textBox) contains(x, y int) bool {
+	return x >= box.x1 && x <= box.x2 && y >= box.y1 && y <= box.bottom(box.lines())
+}
+
+func (box *textBox) lineOffset(line textLine) int {
+	remainingWidth := box.x2 - box.x1 + 1 - textWidth(box.text[line.start:line.end])
+	if remainingWidth < 0 {
+		remainingWidth = 0
+	}
+	switch box.alignment {
+	case "center":
+		return remainingWidth / 2
+	case "right":
+		return remainingWidth
+	}
+	return 0
+}
+
+func (box *textBox) cursorLine(lines []textLine) int {
+	for index, line := range lines {
+		if box.cursor >= line.start && (box.cursor < line.end ||
+			(box.cursor == line.end && (index == len(lines)-1 || lines[index+1].start > line.end))) {
+			return index
+		}
+	}
+	return len(lines) - 1
+}
+
+func (box *textBox) cursorPosition() (int, int) {
+	lines := box.lines()
+	index := box.cursorLine(lines)
+	line := lines[index]
+	return box.x1 + box.lineOffset(line) + textWidth(box.text[line.start:box.cursor]), box.y1 + index
+}
+
+func (box *textBox) moveCursorTo(x, y int) {
+	lines := box.lines()
+	index := y - box.y1
+	if index < 0 {
+		index = 0
+	} else if index >= len(lines) {
+		index = len(lines) - 1
+	}
+	line := lines[index]
+	column := x - box.x1 - box.lineOffset(line)
+	box.cursor = line.start
+	for width := 0; box.cursor < line.end; box.cursor++ {
+		letterWidth := runewidth.RuneWidth(box.text[box.cursor])
+		if width+letterWidth/2 >= column {
+			break
+		}
+		width += letterWidth
+	}
+}
+
+func (box *textBox) insert(letter rune) {
+	box.text = append(box.text[:box.cursor], append([]rune{letter}, box.text[box.cursor:]...)...)
+	box.cursor++
+}
+
+func (box *textBox) handleKey(event *tcell.EventKey) bool {
+	x, y := box.cursorPosition()
+	switch event.Key() {
+	case tcell.KeyEscape:
+		return true
+	case tcell.KeyEnter:
+		box.insert('\n')
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		if box.cursor > 0 {
+			box.text = append(box.text[:box.cursor-1], box.text[box.cursor:]...)
+			box.cursor--
+		}
+	case tcell.KeyDelete:
+		if box.cursor < len(box.text) {
+			box.text = append(box.text[:box.cursor], box.text[box.cursor+1:]...)
+		}
+	case tcell.KeyLeft:
+		if box.cursor > 0 {
+			box.cursor--
+		}
+	case tcell.KeyRight:
+		if box.cursor < len(box.text) {
+			box.cursor++
+		}
+	case tcell.KeyUp:
+		if y > box.y1 {
+			box.moveCursorTo(x, y-1)
+		}
+	case tcell.KeyDown:
+		if y < box.y1+len(box.lines())-1 {
+			box.moveCursorTo(x, y+1)
+		}
+	case tcell.KeyHome:
+		box.moveCursorTo(box.x1-1, y)
+	case tcell.KeyEnd:
+		box.moveCursorTo(box.x2+1, y)
+	case tcell.KeyCtrlL:
+		box.alignment, textAlignment = "left", "left"
+	case tcell.KeyCtrlE:
+		box.alignment, textAlignment = "center", "center"
+	case tcell.KeyCtrlR:
+		box.alignment, textAlignment = "right", "right"
+	case tcell.KeyRune:
+		box.insert(event.Rune())
+	}
+	return false
+}
+
+func (box *textBox) cellStyle(x, y int) tcell.Style {
+	_, backgroundColor, _ := box.style.Decompose()
+	if normalizeColor(backgroundColor) != tcell.ColorDefault {
+		return box.style
+	}
+	_, existingStyle, _ := canvas.get(x, y)
+	existingForegroundColor, existingBackgroundColor, _ := existingStyle.Decompose()
+	if normalizeColor(existingBackgroundColor) == tcell.ColorDefault {
+		existingBackgroundColor = existingForegroundColor
+	}
+	return box.style.Background(existingBackgroundColor)
+}
+
+func (box *textBox) layout() map[point]cell {
+	cells := make(map[point]cell)
+	for index, line := range box.lines() {
+		x := box.x1 + box.lineOffset(line)
+		for _, letter := range box.text[line.start:line.end] {
+			style := box.cellStyle(x, box.y1+index)
+			if !transparentCell(letter, style) {
+				cells[point{x, box.y1 + index}] = cell{letter, style}
+			}
+			x += runewidth.RuneWidth(letter)
+		}
+	}
+	return cells
+}
+
+func (box *textBox) draw(screen tcell.Screen) {
+	bottom := box.bottom(box.lines())
+	outlineStyle := tcell.StyleDefault.Foreground(tcell.ColorGray)
+	for col := box.x1 - 1; col <= box.x2+1; col++ {
+		if box.y1 > toolbarHeight {
+			screen.SetContent(col, box.y1-1, '┄', nil, outlineStyle)
+		}
+		screen.SetContent(col, bottom+1, '┄', nil, outlineStyle)
+	}
+	for row := box.y1; row <= bottom; row++ {
+		screen.SetContent(box.x1-1, row, '┆', nil, outlineStyle)
+		screen.SetContent(box.x2+1, row, '┆', nil, outlineStyle)
+	}
+
+	colorCount := screen.Colors()
+	for cellPoint, textCell := range box.layout() {
+		screen.SetContent(cellPoint.x, cellPoint.y, textCell.character, nil, adaptStyle(textCell.style, colorCount))
+	}
+	screen.ShowCursor(box.cursorPosition())
+}
+
+func (box *textBox) restore(screen tcell.Screen) {
+	for _, cellPoint := range sortedCellPoints(box.rendered) {
+		renderedCell := box.rendered[cellPoint]
+		character, style, ok := canvas.get(cellPoint.x, cellPoint.y)
+		if !ok || character != renderedCell.character || style != renderedCell.style {
+			continue
+		}
+		if backdropCell, ok := box.backdrop[cellPoint]; ok {
+			setContent(screen, cellPoint.x, cellPoint.y, backdropCell.character, backdropCell.style, false)
+		} else {
+			setContent(screen, cellPoint.x, cellPoint.y, ' ', tcell.StyleDefault, false)
+		}
+	}
+}
+
+func (box *textBox) edit(screen tcell.Screen) *textBox {
+	box.restore(screen)
+	delete(textBoxes, box.id)
+	box.cursor = len(box.text)
+	return box
+}
+
+func commitText(screen tcell.Screen, box *textBox, send bool) {
+	if previousBox, ok := textBoxes[box.id]; ok {
+		previousBox.restore(screen)
+		delete(textBoxes, box.id)
+	} else if len(box.text) == 0 && box.backdrop == nil {
+		return
+	}
+
+	box.rendered = box.layout()
+	box.backdrop = make(map[point]cell)
+	for cellPoint := range box.rendered {
+		if character, style, ok := canvas.get(cellPoint.x, cellPoint.y); ok {
+			box.backdrop[cellPoint] = cell{character, style}
+		}
+	}
+	for _, cellPoint := range sortedCellPoints(box.rendered) {
+		setContent(screen, cellPoint.x, cellPoint.y, box.rendered[cellPoint].character, box.rendered[cellPoint].style, false)
+	}
+	if len(box.text) > 0 {
+		textBoxes[box.id] = box
+	}
+
+	if len(connections) > 0 && send {
+		foregroundColorName, backgroundColorName, attributes := formatStyle(box.style)
+		broadcastOnLayer(fmt.Sprintf(
+			"text:%v,%v,%v,%v,%v,%v,%v,%v,%v,%v\n",
+			box.id,
+			box.x1,
+			box.y1,
+			box.x2,
+			box.y2,
+			box.alignment,
+			foregroundColorName,
+			backgroundColorName,
+			attributes,
+			escapeText(string(box.text)),
+		))
+	}
+}
@@ -0,0 +1,709 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+const (
+	toolbarHeight = 4
+	menuLabel     = "≡"
+)
+
+type toolbarWidget interface {
+	widths() (int, int)
+	priority() int
+	draw(screen tcell.Screen, x, width int, hover point)
+	handleMouse(x, y, width int, button tcell.ButtonMask)
+	tooltip(x, y, width int) string
+}
+
+type toolbarSlot struct {
+	widget   toolbarWidget
+	x, width int
+}
+
+type toolbar struct {
+	widgets []toolbarWidget
+	slots   []toolbarSlot
+	menu    *toolbarMenu
+}
+
+type toolbarButton struct {
+	label       string
+	style       tcell.Style
+	description func() string
+	binding     string
+	selected    func() bool
+	activate    func()
+}
+
+type buttonGroup struct {
+	title      string
+	padding    int
+	spacing    int
+	buttons    []toolbarButton
+	importance int
+}
+
+type colorBoxWidget struct {
+	pick func(target *string)
+}
+
+type brushBoxWidget struct {
+	pick func()
+}
+
+type paletteWidget struct {
+	colors     func() []string
+	length     int
+	scroll     *int
+	importance int
+}
+
+type connectionsWidget struct{}
+
+type toolbarMenu struct {
+	groups []*buttonGroup
+	open   func(dialog modal)
+}
+
+type menuDialog struct {
+	x, y   int
+	groups []*buttonGroup
+	hover  point
+	chosen *toolbarButton
+}
+
+var (
+	toolDescriptions = map[string]string{
+		"Pencil":  "Draw freehand (right click to erase)",
+		"Line":    "Draw straight lines",
+		"Region":  "Draw filled rectangles",
+		"Border":  "Draw boxes (click again for border styles)",
+		"Ellipse": "Draw ellipses (click again to switch to circles)",
+		"Disc":    "Draw filled ellipses (click again to switch to circles)",
+		"Fill":    "Fill enclosed areas (click again for options)",
+		"Select":  "Select, move, copy and paste parts of the canvas",
+		"Stamp":   "Place saved stamps",
+		"Banner":  "Write big text with FIGlet fonts",
+		"Text":    "Write text in text boxes",
+	}
+	actionDescriptions = map[string]string{
+		"Save":    "Save the canvas to a file",
+		"Load":    "Load a canvas from a file",
+		"Palette": "Load a palette file",
+		"Layers":  "Add, remove and reorder layers",
+		"Mirror":  "Cycle symmetry modes",
+		"Clear":   "Clear the current layer",
+		"Exit":    "Exit termcanvas",
+	}
+	attributeNames = []string{"Bold", "Italic", "Underline", "Reverse", "Blink"}
+	labelStyle     = tcell.StyleDefault.Foreground(tcell.ColorWhite)
+)
+
+func layoutToolbar(widgets []toolbarWidget, width int) ([]toolbarSlot, []*buttonGroup) {
+	hidden := make(map[int]bool)
+	hiddenGroups := false
+	for {
+		total := 0
+		for index, widget := range widgets {
+			if !hidden[index] {
+				minimumWidth, _ := widget.widths()
+				total += minimumWidth
+			}
+		}
+		if hiddenGroups {
+			total += len(menuLabel) + 2
+		}
+		if total <= width {
+			break
+		}
+
+		hideIndex := -1
+		for index, widget := range widgets {
+			if hidden[index] || widget.priority() == 0 {
+				continue
+			}
+			if hideIndex == -1 || widget.priority() <= widgets[hideIndex].priority() {
+				hideIndex = index
+			}
+		}
+		if hideIndex == -1 {
+			break
+		}
+		hidden[hideIndex] = true
+		if _, ok := widgets[hideIndex].(*buttonGroup); ok {
+			hiddenGroups = true
+		}
+	}
+
+	var collapsed []*buttonGroup
+	for index, widget := range widgets {
+		if group, ok := widget.(*buttonGroup); ok && hidden[index] {
+			collapsed = append(collapsed, group)
+		}
+	}
+
+	remainingWidth := width
+	if len(collapsed) > 0 {
+		remainingWidth -= len(menuLabel) + 2
+	}
+	for index, widget := range widgets {
+		if !hidden[index] {
+			minimumWidth, _ := widget.widths()
+			remainingWidth -= minimumWidth
+		}
+	}
+	var slots []toolbarSlot
+	x := 0
+	for index, widget := range widgets {
+		if hidden[index] {
+			continue
+		}
+		minimumWidth, preferredWidth := widget.widths()
+		widgetWidth := minimumWidth
+		if extraWidth := preferredWidth - minimumWidth; extraWidth > 0 && remainingWidth > 0 {
+			if extraWidth > remainingWidth {
+				extraWidth = remainingWidth
+			}
+			widgetWidth += extraWidth
+			remainingWidth -= extraWidth
+		}
+		if widgetWidth > 0 {
+			slots = append(slots, toolbarSlot{widget, x, widgetWidth})
+		}
+		x += widgetWidth
+	}
+	return slots, collapsed
+}
+
+func (bar *toolbar) layout(width int) {
+	slots, collapsed := layoutToolbar(bar.widgets, width)
+	if len(collapsed) > 0 {
+		bar.menu.groups = collapsed
+		slots = append(slots, toolbarSlot{bar.menu, width - len(menuLabel) - 2, len(menuLabel) + 2})
+	}
+	bar.slots = slots
+}
+
+func (bar *toolbar) slotAt(x, y int) (toolbarSlot, bool) {
+	if y < 0 || y >= toolbarHeight {
+		return toolbarSlot{}, false
+	}
+	for _, slot := range bar.slots {
+		if x >= slot.x && x < slot.x+slot.width {
+			return slot, true
+		}
+	}
+	return toolbarSlot{}, false
+}
+
+func (bar *toolbar) draw(screen tcell.Screen, hoverX, hoverY int) {
+	width, _ := screen.Size()
+	defaultStyle := tcell.StyleDefault.
+		Background(tcell.ColorReset).
+		Foreground(tcell.ColorReset)
+	for row := 0; row < toolbarHeight; row++ {
+		for col := 0; col < width; col++ {
+			setContent(screen, col, row, ' ', defaultStyle, false)
+		}
+	}
+	for _, slot := range bar.slots {
+		hover := point{-1, -1}
+		if hoverY >= 0 && hoverY < toolbarHeight && hoverX >= slot.x && hoverX < slot.x+slot.width {
+			hover = point{hoverX - slot.x, hoverY}
+		}
+		slot.widget.draw(screen, slot.x, slot.width, hover)
+	}
+}
+
+func (bar *toolbar) handleMouse(x, y int, button tcell.ButtonMask) {
+	if slot, ok := bar.slotAt(x, y); ok {
+		slot.widget.handleMouse(x-slot.x, y, slot.width, button)
+	}
+}
+
+func (bar *toolbar) tooltip(x, y int) string {
+	if slot, ok := bar.slotAt(x, y); ok {
+		return slot.widget.tooltip(x-slot.x, y, slot.width)
+	}
+	return ""
+}
+
+func drawTooltip(screen tcell.Screen, x, y int, text string) {
+	width, _ := screen.Size()
+	text = " " + text + " "
+	if x+len([]rune(text)) > width {
+		x = width - len([]rune(text))
+	}
+	if x < 0 {
+		x = 0
+	}
+	drawText(screen, x, y, text, tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.ColorWhite))
+}
+
+func bindingHint(action string) string {
+	if keys := keyBindings[action]; len(keys) > 0 {
+		return " [" + keys[0] + "]"
+	}
+	return ""
+}
+
+func fillToolbarColumn(screen tcell.Screen, x int, letter rune, style tcell.Style) {
+	setContent(screen, x, 1, letter, style, false)
+	setContent(screen, x, 2, letter, style, false)
+}
+
+func toolButtons(selectTool func(tool string)) *buttonGroup {
+	group := &buttonGroup{title: "Tools", padding: 1, spacing: 2, importance: 5}
+	for _, tool := range tools {
+		tool := tool
+		group.buttons = append(group.buttons, toolbarButton{
+			label:       tool,
+			style:       labelStyle,
+			description: func() string { return toolDescriptions[tool] },
+			binding:     strings.ToLower(tool),
+			selected:    func() bool { return selectedTool == tool },
+			activate:    func() { selectTool(tool) },
+		})
+	}
+	return group
+}
+
+func attributeButtons() *buttonGroup {
+	group := &buttonGroup{title: "Attributes", padding: 2, spacing: 1, importance: 4}
+	for index, attribute := range attributes {
+		attribute, name := attribute, attributeNames[index]
+		group.buttons = append(group.buttons, toolbarButton{
+			label:       string(strings.ToUpper(attributeLetters)[index]),
+			style:       labelStyle.Attributes(attribute),
+			description: func() string { return name + " text" },
+			binding:     strings.ToLower(name),
+			selected:    func() bool { return selectedAttributes&attribute != 0 },
+			activate:    func() { selectedAttributes ^= attribute },
+		})
+	}
+	return group
+}
+
+func actionButtons(runAction func(action string)) *buttonGroup {
+	group := &buttonGroup{title: "Actions", padding: 1, spacing: 2, importance: 3}
+	for _, action := range actions {
+		action := action
+		button := toolbarButton{
+			label:       action,
+			style:       labelStyle,
+			description: func() string { return actionDescriptions[action] },
+			binding:     strings.ToLower(action),
+			activate:    func() { runAction(action) },
+		}
+		if action == "Mirror" {
+			button.description = func() string {
+				return actionDescriptions[action] + " (" + currentSymmetry.mode + ")"
+			}
+			button.selected = func() bool { return currentSymmetry.mode != "off" }
+		}
+		group.buttons = append(group.buttons, button)
+	}
+	return group
+}
+
+func (group *buttonGroup) widths() (int, int) {
+	width := group.padding * 2
+	for index, button := range group.buttons {
+		if index > 0 {
+			width += group.spacing
+		}
+		width += len([]rune(button.label))
+	}
+	return width, width
+}
+
+func (group *buttonGroup) priority() int {
+	return group.importance
+}
+
+func (group *buttonGroup) buttonAt(x int) int {
+	offset := group.padding
+	for index, button := range group.buttons {
+		labelWidth := len([]rune(button.label))
+		if x >= offset-group.spacing/2 && x < offset+labelWidth+group.spacing/2 {
+			return index
+		}
+		offset += labelWidth + group.spacing
+	}
+	return -1
+}
+
+func (group *buttonGroup) draw(screen tcell.Screen, x, width int, hover point) {
+	defaultStyle := tcell.StyleDefault.
+		Background(tcell.ColorReset).
+		Foreground(tcell.ColorReset)
+	drawRegion(screen, x, 0, x+width-1, toolbarHeight-1, defaultStyle, defaultStyle, ' ', true, false)
+	hoveredIndex := -1
+	if hover.y >= 0 {
+		hoveredIndex = group.buttonAt(hover.x)
+	}
+	offset := x + group.padding
+	for index, button := range group.buttons {
+		style := button.style
+		if index == hoveredIndex {
+			style = style.Reverse(true)
+		}
+		for letterOffset, letter := range []rune(button.label) {
+			setContent(screen, offset+letterOffset, 1, letter, style, false)
+			if button.selected != nil && button.selected() {
+				setContent(screen, offset+letterOffset, 2, '^', labelStyle, false)
+			}
+		}
+		offset += len([]rune(button.label)) + group.spacing
+	}
+}
+
+func (group *buttonGroup) handleMouse(x, y, width int, button tcell.ButtonMask) {
+	if button != tcell.Button1 {
+		return
+	}
+	if index := group.buttonAt(x); index != -1 {
+		group.buttons[index].activate()
+	}
+}
+
+func (group *buttonGroup) tooltip(x, y, width int) string {
+	if index := group.buttonAt(x); index != -1 {
+		button := group.buttons[index]
+		return button.label + ": " + button.description() + bindingHint(button.binding)
+	}
+	return ""
+}
+
+func (widget *colorBoxWidget) widths() (int, int) {
+	return 6, 6
+}
+
+func (widget *colorBoxWidget) priority() int {
+	return 0
+}
+
+func (widget *colorBoxWidget) draw(screen tcell.Screen, x, width int, hover point) {
+	defaultStyle := tcell.StyleDefault.
+		Background(tcell.ColorReset).
+		Foreground(tcell.ColorReset)
+	drawRegion(screen, x, 0, x+width-1, toolbarHeight-1, defaultStyle, defaultStyle, ' ', true, false)
+	for col := x + 1; col < x+width-1; col++ {
+		setContent(screen, col, 1, block, tcell.StyleDefault.Foreground(tcell.GetColor(primaryColor)), false)
+		if secondaryColor == "reset" {
+			setContent(screen, col, 2, '░', defaultStyle, false)
+		} else {
+			setContent(screen, col, 2, block, tcell.StyleDefault.Foreground(tcell.GetColor(secondaryColor)), false)
+		}
+	}
+}
+
+func (widget *colorBoxWidget) handleMouse(x, y, width int, button tcell.ButtonMask) {
+	if button == tcell.Button1 {
+		if y >= 2 {
+			widget.pick(&secondaryColor)
+		} else {
+			widget.pick(&primaryColor)
+		}
+	} else if button == tcell.Button2 {
+		secondaryColor = "reset"
+	}
+}
+
+func (widget *colorBoxWidget) tooltip(x, y, width int) string {
+	if y >= 2 {
+		return "Secondary color: " + secondaryColor + " (click to edit, right click to clear)" +
+			bindingHint("secondary-color-picker")
+	}
+	return "Primary color: " + primaryColor + " (click to edit)" + bindingHint("color-picker")
+}
+
+func (widget *brushBoxWidget) widths() (int, int) {
+	return 4, 4
+}
+
+func (widget *brushBoxWidget) priority() int {
+	return 0
+}
+
+func (widget *brushBoxWidget) draw(screen tcell.Screen, x, width int, hover point) {
+	defaultStyle := tcell.StyleDefault.
+		Background(tcell.ColorReset).
+		Foreground(tcell.ColorReset)
+	drawRegion(screen, x, 0, x+width-1, toolbarHeight-1, defaultStyle, defaultStyle, ' ', true, false)
+	setContent(screen, x+1, 1, brush, paintStyle(), false)
+}
+
+func (widget *brushBoxWidget) handleMouse(x, y, width int, button tcell.ButtonMask) {
+	if button == tcell.Button1 {
+		widget.pick()
+	}
+}
+
+func (widget *brushBoxWidget) tooltip(x, y, width int) string {
+	return "Brush: " + string(brush) + " (click to pick another one)" + bindingHint("brush-picker")
+}
+
+func (widget *paletteWidget) widths() (int, int) {
+	if widget.length > 0 {
+		return widget.length + 2, widget.length + 2
+	}
+	colorsLength := len(widget.colors())
+	if colorsLength > 3 {
+		return 3 + 2, colorsLength + 2
+	}
+	return colorsLength + 2, colorsLength + 2
+}
+
+func (widget *paletteWidget) priority() int {
+	return widget.importance
+}
+
+func (widget *paletteWidget) visibleColors(width int) (int, int) {
+	colorsLength := width - 2
+	if widget.scroll == nil {
+		return 0, colorsLength
+	}
+	paletteColors := widget.colors()
+	if *widget.scroll > len(paletteColors)-colorsLength {
+		*widget.scroll = len(paletteColors) - colorsLength
+	}
+	if *widget.scroll < 0 {
+		*widget.scroll = 0
+	}
+	return *widget.scroll, colorsLength
+}
+
+func (widget *paletteWidget) colorAt(x, width int) (int, bool, bool) {
+	scroll, colorsLength := widget.visibleColors(width)
+	column := x - 1
+	if column < 0 || column >= colorsLength || scroll+column >= len(widget.colors()) {
+		return -1, false, false
+	}
+	if column == 0 && scroll > 0 {
+		return -1, true, false
+	}
+	if column == colorsLength-1 && scroll+colorsLength < len(widget.colors()) {
+		return -1, false, true
+	}
+	return scroll + column, false, false
+}
+
+func (widget *paletteWidget) draw(screen tcell.Screen, x, width int, hover point) {
+	defaultStyle := tcell.StyleDefault.
+		Background(tcell.ColorReset).
+		Foreground(tcell.ColorReset)
+	drawRegion(screen, x, 0, x+width-1, toolbarHeight-1, defaultStyle, defaultStyle, ' ', true, false)
+	scroll, colorsLength := widget.visibleColors(width)
+	paletteColors := widget.colors()
+	for column := 0; column < colorsLength && scroll+column < len(paletteColors); column++ {
+		fillToolbarColumn(screen, x+1+column, block, tcell.StyleDefault.Foreground(tcell.GetColor(paletteColors[scroll+column])))
+	}
+	if scroll > 0 {
+		fillToolbarColumn(screen, x+1, '◀', labelStyle)
+	}
+	if scroll+colorsLength < len(paletteColors) {
+		fillToolbarColumn(screen, x+colorsLength, '▶', labelStyle)
+	}
+	if hover.y >= 1 && hover.y <= 2 {
+		if index, _, _ := widget.colorAt(hover.x, width); index != -1 {
+			setContent(screen, x+hover.x, 3, '^', labelStyle, false)
+		}
+	}
+}
+
+func (widget *paletteWidget) handleMouse(x, y, width int, button tcell.ButtonMask) {
+	index, scrollLeft, scrollRight := widget.colorAt(x, width)
+	switch button {
+	case tcell.Button1:
+		if scrollLeft {
+			*widget.scroll--
+		} else if scrollRight {
+			*widget.scroll++
+		} else if index != -1 {
+			primaryColor = widget.colors()[index]
+		}
+	case tcell.Button2:
+		if index != -1 {
+			secondaryColor = widget.colors()[index]
+		}
+	case tcell.WheelUp, tcell.WheelDown:
+		if widget.scroll != nil {
+			if button == tcell.WheelUp {
+				*widget.scroll--
+			} else {
+				*widget.scroll++
+			}
+		}
+	}
+}
+
+func (widget *paletteWidget) tooltip(x, y, width int) string {
+	index, scrollLeft, scrollRight := widget.colorAt(x, width)
+	if scrollLeft || scrollRight {
+		return "Scroll the palette (or use the mouse wheel)"
+	} else if index != -1 {
+		return widget.colors()[index] + " (left click: primary color, right click: secondary color)"
+	}
+	return ""
+}
+
+func (widget *connectionsWidget) names() string {
+	names := ""
+	for _, connection := range connections {
+		names += connectionName(connection) + ", "
+	}
+	return strings.Trim(names, ", ")
+}
+
+func (widget *connectionsWidget) widths() (int, int) {
+	if len(connections) == 0 {
+		return 0, 0
+	}
+	title := "Connected to:"
+	names := widget.names()
+	if len([]rune(names)) > len(title) {
+		return len(title) + 2, len([]rune(names)) + 2
+	}
+	return len(title) + 2, len(title) + 2
+}
+
+func (widget *connectionsWidget) priority() int {
+	return 1
+}
+
+func (widget *connectionsWidget) draw(screen tcell.Screen, x, width int, hover point) {
+	drawText(screen, x+2, 1, "Connected to:", labelStyle)
+	names := []rune(widget.names())
+	if len(names) > width-2 {
+		names = append(names[:width-3], '…')
+	}
+	drawText(screen, x+2, 2, string(names), labelStyle)
+}
+
+func (widget *connectionsWidget) handleMouse(x, y, width int, button tcell.ButtonMask) {}
+
+func (widget *connectionsWidget) tooltip(x, y, width int) string {
+	return widget.names()
+}
+
+func (menu *toolbarMenu) widths() (int, int) {
+	return len(menuLabel) + 2, len(menuLabel) + 2
+}
+
+func (menu *toolbarMenu) priority() int {
+	return 0
+}
+
+func (menu *toolbarMenu) draw(screen tcell.Screen, x, width int, hover point) {
+	defaultStyle := tcell.StyleDefault.
+		Background(tcell.ColorReset).
+		Foreground(tcell.ColorReset)
+	drawRegion(screen, x, 0, x+width-1, toolbarHeight-1, defaultStyle, defaultStyle, ' ', true, false)
+	style := labelStyle
+	if hover.y >= 0 {
+		style = style.Reverse(true)
+	}
+	drawText(screen, x+1, 1, menuLabel, style)
+}
+
+func (menu *toolbarMenu) handleMouse(x, y, width int, button tcell.ButtonMask) {
+	if button == tcell.Button1 {
+		menu.open(&menuDialog{groups: menu.groups, hover: point{-1, -1}})
+	}
+}
+
+func (menu *toolbarMenu) tooltip(x, y, width int) string {
+	titles := make([]string, len(menu.groups))
+	for index, group := range menu.groups {
+		titles[index] = group.title
+	}
+	return "More: " + strings.Join(titles, ", ")
+}
+
+func (dialog *menuDialog) columnWidth(group *buttonGroup) int {
+	width := len(group.title)
+	for _, button := range group.buttons {
+		if labelWidth := len([]rune(button.label)) + 2; labelWidth > width {
+			width = labelWidth
+		}
+	}
+	return width + 2
+}
+
+func (dialog *menuDialog) size() (int, int) {
+	width, height := 1, 0
+	for _, group := range dialog.groups {
+		width += dialog.columnWidth(group)
+		if len(group.buttons) > height {
+			height = len(group.buttons)
+		}
+	}
+	return width + 1, height + 4
+}
+
+func (dialog *menuDialog) buttonAt(x, y int) *toolbarButton {
+	row := y - dialog.y - 2
+	col := dialog.x + 1
+	for _, group := range dialog.groups {
+		columnWidth := dialog.columnWidth(group)
+		if x >= col && x < col+columnWidth && row >= 0 && row < len(group.buttons) {
+			return &group.buttons[row]
+		}
+		col += columnWidth
+	}
+	return nil
+}
+
+func (dialog *menuDialog) draw(screen tcell.Screen) {
+	screenWidth, _ := screen.Size()
+	dialogWidth, dialogHeight := dialog.size()
+	dialog.x, dialog.y = screenWidth-dialogWidth, toolbarHeight
+	if dialog.x < 0 {
+		dialog.x = 0
+	}
+
+	drawBox(screen, dialog.x, dialog.y, dialog.x+dialogWidth-1, dialog.y+dialogHeight-1, "", labelStyle)
+	hovered := dialog.buttonAt(dialog.hover.x, dialog.hover.y)
+	col := dialog.x + 2
+	for _, group := range dialog.groups {
+		drawText(screen, col, dialog.y+1, group.title, tcell.StyleDefault.Foreground(tcell.ColorGray))
+		for index := range group.buttons {
+			button := &group.buttons[index]
+			marker := "  "
+			if button.selected != nil && button.selected() {
+				marker = "• "
+			}
+			style := button.style
+			if button == hovered {
+				style = style.Reverse(true)
+			}
+			drawText(screen, col, dialog.y+2+index, marker, labelStyle)
+			drawText(screen, col+2, dialog.y+2+index, button.label, style)
+		}
+		col += dialog.columnWidth(group)
+	}
+	if hovered != nil {
+		drawTooltip(screen, dialog.x, dialog.y+dialogHeight, hovered.description()+bindingHint(hovered.binding))
+	}
+}
+
+func (dialog *menuDialog) handleEvent(event tcell.Event) bool {
+	switch event := event.(type) {
+	case *tcell.EventKey:
+		return event.Key() == tcell.KeyEscape
+	case *tcell.EventMouse:
+		x, y := event.Position()
+		dialog.hover = point{x, y}
+		if event.Buttons() != tcell.Button1 {
+			return false
+		}
+		dialog.chosen = dialog.buttonAt(x, y)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func testButton(label, binding string) toolbarButton {
+	return toolbarButton{
+		label:       label,
+		style:       labelStyle,
+		description: func() string { return toolDescriptions[label] },
+		binding:     binding,
+	}
+}
+
+func testWidgets() (*colorBoxWidget, *buttonGroup, *buttonGroup, *buttonGroup) {
+	tools := &buttonGroup{
+		title:      "Tools",
+		padding:    1,
+		spacing:    2,
+		buttons:    []toolbarButton{testButton("Pencil", "pencil"), testButton("Line", "line")},
+		importance: 5,
+	}
+	attributes := &buttonGroup{
+		title:      "Attributes",
+		padding:    1,
+		spacing:    1,
+		buttons:    []toolbarButton{testButton("B", ""), testButton("I", "")},
+		importance: 4,
+	}
+	actions := &buttonGroup{
+		title:      "Actions",
+		padding:    1,
+		spacing:    2,
+		buttons:    []toolbarButton{testButton("Save", ""), testButton("Exit", "")},
+		importance: 3,
+	}
+	return &colorBoxWidget{}, tools, attributes, actions
+}
+
+func TestLayoutToolbarCollapse(t *testing.T) {
+	colorBox, tools, attributes, actions := testWidgets()
+	widgets := []toolbarWidget{colorBox, tools, attributes, actions}
+
+	tests := []struct {
+		width     int
+		visible   []toolbarWidget
+		collapsed []*buttonGroup
+	}{
+		{37, []toolbarWidget{colorBox, tools, attributes, actions}, nil},
+		{36, []toolbarWidget{colorBox, tools, attributes}, []*buttonGroup{actions}},
+		{30, []toolbarWidget{colorBox, tools, attributes}, []*buttonGroup{actions}},
+		{29, []toolbarWidget{colorBox, tools}, []*buttonGroup{attributes, actions}},
+		{25, []toolbarWidget{colorBox, tools}, []*buttonGroup{attributes, actions}},
+		{24, []toolbarWidget{colorBox}, []*buttonGroup{tools, attributes, actions}},
+		{4, []toolbarWidget{colorBox}, []*buttonGroup{tools, attributes, actions}},
+	}
+	for _, test := range tests {
+		slots, collapsed := layoutToolbar(widgets, test.width)
+		if len(slots) != len(test.visible) {
+			t.Errorf("width %v: got %v slots, want %v", test.width, len(slots), len(test.visible))
+			continue
+		}
+		for index, slot := range slots {
+			if slot.widget != test.visible[index] {
+				t.Errorf("width %v: slot %v holds the wrong widget", test.width, index)
+			}
+		}
+		if len(collapsed) != len(test.collapsed) {
+			t.Errorf("width %v: got %v collapsed groups, want %v", test.width, len(collapsed), len(test.collapsed))
+			continue
+		}
+		for index, group := range collapsed {
+			if group != test.collapsed[index] {
+				t.Errorf("width %v: collapsed group %v is %v, want %v", test.width, index, group.title, test.collapsed[index].title)
+			}
+		}
+	}
+}
+
+func TestLayoutToolbarOrder(t *testing.T) {
+	colorBox, tools, attributes, actions := testWidgets()
+	orders := [][]toolbarWidget{
+		{colorBox, tools, attributes, actions},
+		{actions, attributes, colorBox, tools},
+		{tools, colorBox, actions, attributes},
+	}
+	for _, widgets := range orders {
+		slots, _ := layoutToolbar(widgets, 80)
+		if len(slots) != len(widgets) {
+			t.Fatalf("got %v slots, want %v", len(slots), len(widgets))
+		}
+		x := 0
+		for index, slot := range slots {
+			minimumWidth, _ := widgets[index].widths()
+			if slot.widget != widgets[index] || slot.x != x || slot.width != minimumWidth {
+				t.Errorf("slot %v is at %v with width %v, want %v with width %v", index, slot.x, slot.width, x, minimumWidth)
+			}
+			x += minimumWidth
+		}
+	}
+}
+
+func TestToolbarHover(t *testing.T) {
+	screen := tcell.NewSimulationScreen("")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer screen.Fini()
+	screen.SetSize(40, 10)
+
+	colorBox, tools, attributes, actions := testWidgets()
+	bar := &toolbar{
+		widgets: []toolbarWidget{colorBox, tools, attributes, actions},
+		menu:    &toolbarMenu{open: func(dialog modal) {}},
+	}
+	bar.layout(40)
+
+	bar.draw(screen, 8, 1)
+	for x := 7; x < 7+len("Pencil"); x++ {
+		character, _, style, _ := screen.GetContent(x, 1)
+		if _, _, attributes := style.Decompose(); attributes&tcell.AttrReverse == 0 {
+			t.Errorf("hovered label %q at %v isn't highlighted", character, x)
+		}
+	}
+	for x := 15; x < 15+len("Line"); x++ {
+		character, _, style, _ := screen.GetContent(x, 1)
+		if _, _, attributes := style.Decompose(); attributes&tcell.AttrReverse != 0 {
+			t.Errorf("label %q at %v is highlighted without being hovered", character, x)
+		}
+	}
+	if tooltip := bar.tooltip(8, 1); tooltip != "Pencil: "+toolDescriptions["Pencil"]+" [1]" {
+		t.Errorf("got tooltip %q", tooltip)
+	}
+	if tooltip := bar.tooltip(21, 1); tooltip != "B: " {
+		t.Errorf("got tooltip %q", tooltip)
+	}
+	if tooltip := bar.tooltip(39, 1); tooltip != "" {
+		t.Errorf("got tooltip %q outside of any widget", tooltip)
+	}
+
+	bar.layout(24)
+	bar.draw(screen, 22, 1)
+	menuX := 24 - len(menuLabel) - 2
+	if character, _, style, _ := screen.GetContent(menuX+1, 1); character != '≡' {
+		t.Errorf("got %q instead of the menu label", character)
+	} else if _, _, attributes := style.Decompose(); attributes&tcell.AttrReverse == 0 {
+		t.Error("hovered menu isn't highlighted")
+	}
+	if tooltip := bar.tooltip(22, 1); tooltip != "More: Tools, Attributes, Actions" {
+		t.Errorf("got tooltip %q", tooltip)
+	}
+}
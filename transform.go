@@ -0,0 +1,111 @@
+package main
+
+import "github.com/mattn/go-runewidth"
+
+var (
+	horizontalMirrors = pairedRunes(
+		"┌┐", "└┘", "├┤", "╔╗", "╚╝", "╠╣", "╭╮", "╰╯", "┏┓", "┗┛", "┣┫",
+		"╴╶", "▌▐", "▖▗", "▘▝", "▙▟", "▛▜", "▚▞", "◀▶", "/\\", "()", "[]", "{}", "<>",
+	)
+	verticalMirrors = pairedRunes(
+		"┌└", "┐┘", "┬┴", "╔╚", "╗╝", "╦╩", "╭╰", "╮╯", "┏┗", "┓┛", "┳┻",
+		"╵╷", "▀▄", "▖▘", "▗▝", "▙▛", "▟▜", "▚▞", "▲▼", "/\\", "^v",
+	)
+	clockwiseRotations = cycledRunes(
+		"─│", "═║", "━┃", "┌┐┘└", "├┬┤┴", "╔╗╝╚", "╠╦╣╩", "╭╮╯╰", "┏┓┛┗", "┣┳┫┻",
+		"╴╵╶╷", "▀▐▄▌", "▘▝▗▖", "▛▜▟▙", "▚▞", "▲▶▼◀", "/\\", "-|",
+	)
+)
+
+func pairedRunes(pairs ...string) map[rune]rune {
+	mapping := make(map[rune]rune)
+	for _, pair := range pairs {
+		letters := []rune(pair)
+		mapping[letters[0]] = letters[1]
+		mapping[letters[1]] = letters[0]
+	}
+	return mapping
+}
+
+func cycledRunes(cycles ...string) map[rune]rune {
+	mapping := make(map[rune]rune)
+	for _, cycle := range cycles {
+		letters := []rune(cycle)
+		for index, letter := range letters {
+			mapping[letter] = letters[(index+1)%len(letters)]
+		}
+	}
+	return mapping
+}
+
+func remapRune(letter rune, mapping map[rune]rune) rune {
+	if mappedLetter, ok := mapping[letter]; ok {
+		return mappedLetter
+	}
+	return letter
+}
+
+func normalizeCells(cells map[point]cell) map[point]cell {
+	minX, minY := 0, 0
+	first := true
+	for cellPoint := range cells {
+		if first || cellPoint.x < minX {
+			minX = cellPoint.x
+		}
+		if first || cellPoint.y < minY {
+			minY = cellPoint.y
+		}
+		first = false
+	}
+	normalizedCells := make(map[point]cell)
+	for cellPoint, existingCell := range cells {
+		normalizedCells[point{cellPoint.x - minX, cellPoint.y - minY}] = existingCell
+	}
+	for cellPoint, existingCell := range cells {
+		continuationPoint := point{cellPoint.x - minX + 1, cellPoint.y - minY}
+		if _, ok := normalizedCells[continuationPoint]; !ok && runewidth.RuneWidth(existingCell.character) == 2 {
+			normalizedCells[continuationPoint] = cell{0, existingCell.style}
+		}
+	}
+	return normalizedCells
+}
+
+func flipCellsHorizontally(cells map[point]cell) map[point]cell {
+	width, _ := cellsSize(cells)
+	flippedCells := make(map[point]cell)
+	for cellPoint, existingCell := range cells {
+		if existingCell.character == 0 {
+			continue
+		}
+		x := width - 1 - cellPoint.x
+		if runewidth.RuneWidth(existingCell.character) == 2 {
+			x--
+		}
+		flippedCells[point{x, cellPoint.y}] = cell{remapRune(existingCell.character, horizontalMirrors), existingCell.style}
+	}
+	return normalizeCells(flippedCells)
+}
+
+func flipCellsVertically(cells map[point]cell) map[point]cell {
+	_, height := cellsSize(cells)
+	flippedCells := make(map[point]cell)
+	for cellPoint, existingCell := range cells {
+		if existingCell.character == 0 {
+			continue
+		}
+		flippedCells[point{cellPoint.x, height - 1 - cellPoint.y}] = cell{remapRune(existingCell.character, verticalMirrors), existingCell.style}
+	}
+	return normalizeCells(flippedCells)
+}
+
+func rotateCells(cells map[point]cell) map[point]cell {
+	_, height := cellsSize(cells)
+	rotatedCells := make(map[point]cell)
+	for cellPoint, existingCell := range cells {
+		if existingCell.character == 0 {
+			continue
+		}
+		rotatedCells[point{height - 1 - cellPoint.y, cellPoint.x}] = cell{remapRune(existingCell.character, clockwiseRotations), existingCell.style}
+	}
+	return normalizeCells(rotatedCells)
+}
@@ -0,0 +1,147 @@
+package main
+
+import "github.com/gdamore/tcell/v2"
+
+func drawText(screen tcell.Screen, x, y int, text string, style tcell.Style) {
+	for _, letter := range text {
+		screen.SetContent(x, y, letter, nil, style)
+		x++
+	}
+}
+
+func drawBox(screen tcell.Screen, x1, y1, x2, y2 int, title string, style tcell.Style) {
+	for row := y1; row <= y2; row++ {
+		for col := x1; col <= x2; col++ {
+			screen.SetContent(col, row, ' ', nil, style)
+		}
+	}
+	for col := x1; col <= x2; col++ {
+		screen.SetContent(col, y1, tcell.RuneHLine, nil, style)
+		screen.SetContent(col, y2, tcell.RuneHLine, nil, style)
+	}
+	for row := y1; row <= y2; row++ {
+		screen.SetContent(x1, row, tcell.RuneVLine, nil, style)
+		screen.SetContent(x2, row, tcell.RuneVLine, nil, style)
+	}
+	screen.SetContent(x1, y1, tcell.RuneULCorner, nil, style)
+	screen.SetContent(x2, y1, tcell.RuneURCorner, nil, style)
+	screen.SetContent(x1, y2, tcell.RuneLLCorner, nil, style)
+	screen.SetContent(x2, y2, tcell.RuneLRCorner, nil, style)
+	if title != "" {
+		drawText(screen, x1+2, y1, " "+title+" ", style)
+	}
+}
+
+type modal interface {
+	draw(screen tcell.Screen)
+	handleEvent(event tcell.Event) bool
+}
+
+type textPrompt struct {
+	title    string
+	input    string
+	accepted bool
+	onAccept func(input string)
+}
+
+func centeredBox(screen tcell.Screen, boxWidth, boxHeight int) (int, int) {
+	width, height := screen.Size()
+	x, y := (width-boxWidth)/2, (height-boxHeight)/2
+	if y < toolbarHeight {
+		y = toolbarHeight
+	}
+	if x < 0 {
+		x = 0
+	}
+	return x, y
+}
+
+func (prompt *textPrompt) draw(screen tcell.Screen) {
+	boxWidth := len(prompt.title) + 8
+	if len([]rune(prompt.input))+6 > boxWidth {
+		boxWidth = len([]rune(prompt.input)) + 6
+	}
+	if boxWidth < 40 {
+		boxWidth = 40
+	}
+	x, y := centeredBox(screen, boxWidth, 3)
+	defaultStyle := tcell.StyleDefault.Foreground(tcell.ColorWhite)
+	drawBox(screen, x, y, x+boxWidth-1, y+2, prompt.title, defaultStyle)
+	drawText(screen, x+2, y+1, prompt.input, defaultStyle)
+	screen.SetContent(x+2+len([]rune(prompt.input)), y+1, '_', nil, defaultStyle)
+}
+
+func (prompt *textPrompt) handleEvent(event tcell.Event) bool {
+	if event, ok := event.(*tcell.EventKey); ok {
+		switch event.Key() {
+		case tcell.KeyEscape:
+			return true
+		case tcell.KeyEnter:
+			prompt.accepted = true
+			return true
+		case tcell.KeyBackspace, tcell.KeyBackspace2:
+			if len(prompt.input) > 0 {
+				letters := []rune(prompt.input)
+				prompt.input = string(letters[:len(letters)-1])
+			}
+		case tcell.KeyRune:
+			prompt.input += string(event.Rune())
+		}
+	}
+	return false
+}
+
+type confirmDialog struct {
+	title    string
+	message  string
+	accepted bool
+	onClose  func(accepted bool)
+	x, y     int
+}
+
+const (
+	confirmYesLabel = "[ Yes ]"
+	confirmNoLabel  = "[ No ]"
+)
+
+func (box *confirmDialog) draw(screen tcell.Screen) {
+	boxWidth := len([]rune(box.message)) + 4
+	if len(box.title)+8 > boxWidth {
+		boxWidth = len(box.title) + 8
+	}
+	box.x, box.y = centeredBox(screen, boxWidth, 5)
+	x, y := box.x, box.y
+	defaultStyle := tcell.StyleDefault.Foreground(tcell.ColorWhite)
+	drawBox(screen, x, y, x+boxWidth-1, y+4, box.title, defaultStyle)
+	drawText(screen, x+2, y+1, box.message, defaultStyle)
+	drawText(screen, x+2, y+3, confirmYesLabel, defaultStyle)
+	drawText(screen, x+3+len(confirmYesLabel), y+3, confirmNoLabel, defaultStyle)
+}
+
+func (box *confirmDialog) handleEvent(event tcell.Event) bool {
+	switch event := event.(type) {
+	case *tcell.EventKey:
+		switch {
+		case event.Key() == tcell.KeyEnter || event.Key() == tcell.KeyRune && (event.Rune() == 'y' || event.Rune() == 'Y'):
+			box.accepted = true
+			return true
+		case event.Key() == tcell.KeyEscape || event.Key() == tcell.KeyRune && (event.Rune() == 'n' || event.Rune() == 'N'):
+			return true
+		}
+	case *tcell.EventMouse:
+		if event.Buttons() != tcell.Button1 {
+			return false
+		}
+		mouseX, mouseY := event.Position()
+		x, y := box.x, box.y
+		if mouseY != y+3 {
+			return false
+		}
+		if mouseX >= x+2 && mouseX < x+2+len(confirmYesLabel) {
+			box.accepted = true
+			return true
+		}
+		return mouseX >= x+3+len(confirmYesLabel) && mouseX < x+3+len(confirmYesLabel)+len(confirmNoLabel)
+	}
+	return false
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+)
+
+type zoomLevel struct {
+	name    string
+	scaleX  int
+	scaleY  int
+	sampleX int
+	sampleY int
+}
+
+type canvasView struct {
+	tcell.Screen
+	zoom    int
+	offsetX int
+	offsetY int
+}
+
+const defaultZoom = 2
+
+var (
+	zoomLevels = []zoomLevel{
+		{"25%", 1, 1, 2, 4},
+		{"50%", 1, 1, 1, 2},
+		{"100%", 1, 1, 1, 1},
+		{"200%", 2, 1, 1, 1},
+		{"400%", 4, 2, 1, 1},
+		{"800%", 8, 4, 1, 1},
+	}
+	brailleDots = [4][2]rune{
+		{0x01, 0x08},
+		{0x02, 0x10},
+		{0x04, 0x20},
+		{0x40, 0x80},
+	}
+)
+
+func newCanvasView(screen tcell.Screen) *canvasView {
+	return &canvasView{Screen: screen, zoom: defaultZoom}
+}
+
+func (view *canvasView) level() zoomLevel {
+	return zoomLevels[view.zoom]
+}
+
+func (view *canvasView) setZoom(zoom, x, y int) {
+	if zoom < 0 {
+		zoom = 0
+	} else if zoom > len(zoomLevels)-1 {
+		zoom = len(zoomLevels) - 1
+	}
+	screenX, screenY := view.screenPoint(x, y)
+	view.zoom = zoom
+	level := view.level()
+	marginX, marginY := view.margins()
+	view.offsetX = x - (screenX-marginX)*level.sampleX/level.scaleX
+	view.offsetY = y - toolbarHeight - (screenY-toolbarHeight-marginY)*level.sampleY/level.scaleY
+	view.clampOffset()
+}
+
+func zoomIndex(name string) (int, bool) {
+	for index, level := range zoomLevels {
+		if level.name == name || level.name == name+"%" {
+			return index, true
+		}
+	}
+	return 0, false
+}
+
+func (view *canvasView) margins() (int, int) {
+	if guides.rulers {
+		return rulerWidth, rulerHeight
+	}
+	return 0, 0
+}
+
+func (view *canvasView) canvasColumn(x int) int {
+	level := view.level()
+	marginX, _ := view.margins()
+	return view.offsetX + floorDivide((x-marginX)*level.sampleX, level.scaleX)
+}
+
+func (view *canvasView) canvasRow(y int) int {
+	level := view.level()
+	_, marginY := view.margins()
+	return toolbarHeight + view.offsetY + floorDivide((y-toolbarHeight-marginY)*level.sampleY, level.scaleY)
+}
+
+func (view *canvasView) canvasPoint(x, y int) (int, int) {
+	if y < toolbarHeight {
+		return x, y
+	}
+	return view.canvasColumn(x), view.canvasRow(y)
+}
+
+func (view *canvasView) screenPoint(x, y int) (int, int) {
+	if y < toolbarHeight {
+		return x, y
+	}
+	level := view.level()
+	marginX, marginY := view.margins()
+	x, y = x-view.offsetX, y-toolbarHeight-view.offsetY
+	return marginX + floorDivide(x*level.scaleX, level.sampleX), toolbarHeight + marginY + floorDivide(y*level.scaleY, level.sampleY)
+}
+
+func floorDivide(a, b int) int {
+	if a < 0 && a%b != 0 {
+		return a/b - 1
+	}
+	return a / b
+}
+
+func (view *canvasView) visibleSize() (int, int) {
+	width, height := view.Screen.Size()
+	level := view.level()
+	marginX, marginY := view.margins()
+	width, height = width-marginX, height-marginY
+	return (width*level.sampleX + level.scaleX - 1) / level.scaleX, ((height-toolbarHeight-statusBarHeight)*level.sampleY + level.scaleY - 1) / level.scaleY
+}
+
+func (view *canvasView) Size() (int, int) {
+	visibleWidth, visibleHeight := view.visibleSize()
+	return view.offsetX + visibleWidth, toolbarHeight + view.offsetY + visibleHeight
+}
+
+func (view *canvasView) bounds() (int, int, int, int) {
+	width, height := view.Size()
+	return view.offsetX, toolbarHeight + view.offsetY, width - 1, height - 1
+}
+
+func (view *canvasView) fillBounds() (int, int, int, int) {
+	if width, height := canvas.size(); width != 0 {
+		return 0, toolbarHeight, width - 1, toolbarHeight + height - 1
+	}
+	return view.bounds()
+}
+
+func (view *canvasView) clampOffset() {
+	if width, height := canvas.size(); width != 0 {
+		visibleWidth, visibleHeight := view.visibleSize()
+		if view.offsetX > width-visibleWidth {
+			view.offsetX = width - visibleWidth
+		}
+		if view.offsetY > height-visibleHeight {
+			view.offsetY = height - visibleHeight
+		}
+	}
+	if view.offsetX < 0 {
+		view.offsetX = 0
+	}
+	if view.offsetY < 0 {
+		view.offsetY = 0
+	}
+}
+
+func (view *canvasView) pan(x, y int) {
+	view.offsetX += x
+	view.offsetY += y
+	view.clampOffset()
+}
+
+func (view *canvasView) center(x, y int) {
+	visibleWidth, visibleHeight := view.visibleSize()
+	view.offsetX = x - visibleWidth/2
+	view.offsetY = y - toolbarHeight - visibleHeight/2
+	view.clampOffset()
+}
+
+func (view *canvasView) follow(x, y int) {
+	x1, y1, x2, y2 := view.bounds()
+	if x < x1 {
+		view.pan(x-x1, 0)
+	} else if x > x2 {
+		view.pan(x-x2, 0)
+	}
+	if y < y1 {
+		view.pan(0, y-y1)
+	} else if y > y2 {
+		view.pan(0, y-y2)
+	}
+}
+
+func (view *canvasView) SetContent(x, y int, character rune, combiningCharacters []rune, style tcell.Style) {
+	if y < toolbarHeight {
+		view.Screen.SetContent(x, y, character, combiningCharacters, style)
+		return
+	}
+	level := view.level()
+	if level.sampleX > 1 || level.sampleY > 1 {
+		view.setSample(x, y, character, style)
+		return
+	}
+	if character == 0 {
+		return
+	}
+	screenX, screenY := view.screenPoint(x, y)
+	step := 1
+	if runewidth.RuneWidth(character) == 2 {
+		step = 2
+	}
+	for row := 0; row < level.scaleY; row++ {
+		for col := 0; col < level.scaleX*step; col += step {
+			view.Screen.SetContent(screenX+col, screenY+row, character, combiningCharacters, style)
+		}
+	}
+}
+
+func (view *canvasView) GetContent(x, y int) (rune, []rune, tcell.Style, int) {
+	screenX, screenY := view.screenPoint(x, y)
+	return view.Screen.GetContent(screenX, screenY)
+}
+
+func (view *canvasView) ShowCursor(x, y int) {
+	view.Screen.ShowCursor(view.screenPoint(x, y))
+}
+
+func (view *canvasView) highlight(x, y int) {
+	level := view.level()
+	screenX, screenY := view.screenPoint(x, y)
+	for row := 0; row < level.scaleY; row++ {
+		for col := 0; col < level.scaleX; col++ {
+			character, combiningCharacters, style, _ := view.Screen.GetContent(screenX+col, screenY+row)
+			view.Screen.SetContent(screenX+col, screenY+row, character, combiningCharacters, style.Reverse(true))
+		}
+	}
+}
+
+func sampleColor(character rune, style tcell.Style) (tcell.Color, bool) {
+	foregroundColor, backgroundColor, attributeMask := style.Decompose()
+	if attributeMask&tcell.AttrReverse != 0 {
+		foregroundColor, backgroundColor = backgroundColor, foregroundColor
+	}
+	if character == ' ' {
+		if normalizeColor(backgroundColor) == tcell.ColorDefault {
+			return tcell.ColorDefault, false
+		}
+		return backgroundColor, true
+	}
+	if normalizeColor(foregroundColor) == tcell.ColorDefault {
+		return tcell.ColorWhite, true
+	}
+	return foregroundColor, true
+}
+
+func (view *canvasView) setSample(x, y int, character rune, style tcell.Style) {
+	level := view.level()
+	screenX, screenY := view.screenPoint(x, y)
+	relativeX, relativeY := x-view.offsetX, y-toolbarHeight-view.offsetY
+	sampleX := relativeX - floorDivide(relativeX, level.sampleX)*level.sampleX
+	sampleY := relativeY - floorDivide(relativeY, level.sampleY)*level.sampleY
+	color, filled := sampleColor(character, style)
+	current, _, currentStyle, _ := view.Screen.GetContent(screenX, screenY)
+	currentForeground, currentBackground, _ := currentStyle.Decompose()
+
+	if level.sampleX == 1 {
+		top, bottom := tcell.ColorDefault, tcell.ColorDefault
+		switch current {
+		case '▀':
+			top, bottom = currentForeground, currentBackground
+		case '▄':
+			top, bottom = currentBackground, currentForeground
+		}
+		if sampleY == 0 {
+			top = color
+		} else {
+			bottom = color
+		}
+		switch {
+		case top == tcell.ColorDefault && bottom == tcell.ColorDefault:
+			view.Screen.SetContent(screenX, screenY, ' ', nil, tcell.StyleDefault)
+		case top == tcell.ColorDefault:
+			view.Screen.SetContent(screenX, screenY, '▄', nil, tcell.StyleDefault.Foreground(bottom))
+		default:
+			view.Screen.SetContent(screenX, screenY, '▀', nil, tcell.StyleDefault.Foreground(top).Background(bottom))
+		}
+		return
+	}
+
+	dots := rune(0)
+	if current >= 0x2800 && current <= 0x28FF {
+		dots = current - 0x2800
+	}
+	if filled {
+		dots |= brailleDots[sampleY][sampleX]
+	} else {
+		dots &^= brailleDots[sampleY][sampleX]
+		color = currentForeground
+	}
+	if dots == 0 {
+		view.Screen.SetContent(screenX, screenY, ' ', nil, tcell.StyleDefault)
+		return
+	}
+	view.Screen.SetContent(screenX, screenY, 0x2800+dots, nil, tcell.StyleDefault.Foreground(color))
+}
+
+type cursorEvent struct {
+	*tcell.EventMouse
+}
+
+func newCursorEvent(x, y int, button tcell.ButtonMask) *cursorEvent {
+	return &cursorEvent{tcell.NewEventMouse(x, y, button, tcell.ModNone)}
+}